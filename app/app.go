@@ -0,0 +1,144 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Config — настройки сервера, по умолчанию читаются из переменных окружения TODO_*
+type Config struct {
+	ListenAddress string
+	ListenPort    string
+	DbFilePath    string
+	WebDir        string
+	Password      string
+	JWTSecret     string
+	HolidaysFile  string
+	// Через сколько дней выполненные и архивные задачи удаляются, пустое значение или 0 — хранить всегда
+	ArchiveRetentionDays string
+	// Через сколько дней задачи удаляются из корзины насовсем, пустое значение или 0 — хранить всегда
+	TrashRetentionDays string
+}
+
+// LoadConfig читает настройки из переменных окружения
+func LoadConfig() Config {
+	return Config{
+		ListenAddress:        getenv("TODO_LISTEN_ADDRESS", "127.0.0.1"),
+		ListenPort:           getenv("TODO_PORT", "8080"),
+		DbFilePath:           getenv("TODO_DBFILE_PATH", "./tasks.db"),
+		WebDir:               getenv("TODO_WEB_DIR", "./web"),
+		Password:             getenv("TODO_PASSWORD", ""),
+		JWTSecret:            getenv("TODO_JWT_SECRET", ""),
+		HolidaysFile:         getenv("TODO_HOLIDAYS_FILE", ""),
+		ArchiveRetentionDays: getenv("TODO_ARCHIVE_RETENTION_DAYS", ""),
+		TrashRetentionDays:   getenv("TODO_TRASH_RETENTION_DAYS", "30"),
+	}
+}
+
+func getenv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
+
+// retentionPeriod переводит срок хранения в днях в длительность, пустая строка означает 0
+func retentionPeriod(days string) (time.Duration, error) {
+	if days == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("неправильный срок хранения: %s", days)
+	}
+	return time.Duration(n) * 24 * time.Hour, nil
+}
+
+// server связывает HTTP-обработчики с базой данных и хранилищем задач
+type server struct {
+	config Config
+	db     *sql.DB
+	store  TaskStore
+	// calendar — выходные и праздники для правила b и переноса дат с выходных
+	calendar *calendar
+}
+
+// openDb открывает базу данных и приводит её схему к актуальной версии
+func openDb(config Config) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", config.DbFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы данных: %v", err)
+	}
+
+	applied, err := migrateUp(db, config.DbFilePath)
+	for _, m := range applied {
+		fmt.Printf("Применена миграция %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewServer открывает базу данных из config и возвращает обработчик всех маршрутов
+// планировщика вместе с функцией, которая закрывает базу после остановки сервера
+func NewServer(config Config) (http.Handler, func() error, error) {
+	holidays, err := loadCalendar(config.HolidaysFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archiveRetention, err := retentionPeriod(config.ArchiveRetentionDays)
+	if err != nil {
+		return nil, nil, fmt.Errorf("неправильный срок хранения архива TODO_ARCHIVE_RETENTION_DAYS: %s", config.ArchiveRetentionDays)
+	}
+	trashRetention, err := retentionPeriod(config.TrashRetentionDays)
+	if err != nil {
+		return nil, nil, fmt.Errorf("неправильный срок хранения корзины TODO_TRASH_RETENTION_DAYS: %s", config.TrashRetentionDays)
+	}
+
+	db, err := openDb(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &server{
+		config: config,
+		db:     db,
+		store:  newSQLiteStore(db),
+		// Календарь принадлежит серверу, чтобы серверы в одном процессе не мешали друг другу
+		calendar: holidays,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleMain)
+	mux.HandleFunc("/api/signin", s.handleSignIn)
+	mux.HandleFunc("/api/signup", s.handleSignUp)
+	mux.HandleFunc("/api/task", s.auth(handleTask(s.store, s.calendar)))
+	mux.HandleFunc("/api/tasks", s.auth(handleGetTasks(s.store)))
+	mux.HandleFunc("/api/task/done", s.auth(handleTaskDone(s.store, s.calendar)))
+	mux.HandleFunc("/api/task/skip", s.auth(handleTaskSkip(s.store, s.calendar)))
+	mux.HandleFunc("/api/task/archive", s.auth(handleTaskArchive(s.store)))
+	mux.HandleFunc("/api/task/changes", s.auth(handleTaskChanges(s.store)))
+	mux.HandleFunc("/api/occurrences", s.auth(handleOccurrences(s.store, s.calendar)))
+	mux.HandleFunc("/api/history", s.auth(handleHistory(s.store)))
+	mux.HandleFunc("/api/trash", s.auth(handleTrash(s.store)))
+	mux.HandleFunc("/api/trash/restore", s.auth(handleTrashRestore(s.store)))
+	mux.HandleFunc("/api/undo", s.auth(handleUndo(s.store)))
+	mux.HandleFunc("/api/nextdate", handleNextDate(s.calendar))
+	mux.HandleFunc("/api/repeat/convert", handleRepeatConvert)
+
+	stopRetention := startRetention(s.store, archiveRetention, trashRetention)
+	closeServer := func() error {
+		stopRetention()
+		return db.Close()
+	}
+	return mux, closeServer, nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// TaskChange — запись журнала изменений: старое и новое значение одного поля задачи,
+// кто и когда его изменил
+type TaskChange struct {
+	ID        string `json:"id"`
+	TaskID    string `json:"task_id"`
+	Field     string `json:"field"` // название поля, как в JSON задачи: date, title, repeat...
+	Old       string `json:"old"`
+	New       string `json:"new"`
+	UserID    string `json:"user_id"`         // кто изменил задачу, 0 — общий список
+	Login     string `json:"login,omitempty"` // логин учётной записи; пуст для общего списка и хранилища в памяти
+	ChangedAt string `json:"changed_at"`      // момент изменения в UTC, RFC 3339
+}
+
+// auditField — поле задачи в журнале изменений: название и значение в задаче
+type auditField struct {
+	name  string
+	value func(Task) string
+}
+
+// auditFields — поля задачи, изменения которых записываются в журнал, в порядке записи
+var auditFields = []auditField{
+	{"date", func(t Task) string { return t.Date }},
+	{"time", func(t Task) string { return t.Time }},
+	{"tz", func(t Task) string { return t.TZ }},
+	{"title", func(t Task) string { return t.Title }},
+	{"comment", func(t Task) string { return t.Comment }},
+	{"repeat", func(t Task) string { return t.Repeat }},
+	{"mode", func(t Task) string { return t.Mode }},
+	{"count", func(t Task) string {
+		// Число повторений сравнивается в том виде, в котором его возвращает хранилище
+		if count := repeatCount(t); count > 0 {
+			return strconv.FormatInt(count, 10)
+		}
+		return ""
+	}},
+	{"until", func(t Task) string { return t.Until }},
+	{"exdates", func(t Task) string { return t.Exdates }},
+	{"shift", func(t Task) string { return t.Shift }},
+}
+
+// taskChanges возвращает изменения полей задачи before при замене её на after в момент now
+func taskChanges(before, after Task, now time.Time) []TaskChange {
+	var changes []TaskChange
+	for _, field := range auditFields {
+		old, value := field.value(before), field.value(after)
+		if old == value {
+			continue
+		}
+		changes = append(changes, TaskChange{
+			TaskID:    before.ID,
+			Field:     field.name,
+			Old:       old,
+			New:       value,
+			ChangedAt: timestamp(now),
+		})
+	}
+	return changes
+}
+
+// maxChanges ограничивает число записей в ответе /api/task/changes
+const maxChanges = 500
+
+// handleTaskChanges возвращает журнал изменений задачи id или всех задач пользователя,
+// начиная с последних: только изменения поля field, если оно задано, и не больше limit
+// записей (по умолчанию 50)
+func handleTaskChanges(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+		query := req.URL.Query()
+
+		filter := ChangeFilter{TaskID: query.Get("id"), Field: query.Get("field"), Limit: 50}
+		if filter.Field != "" && !slices.ContainsFunc(auditFields, func(field auditField) bool {
+			return field.name == filter.Field
+		}) {
+			writeError(res, http.StatusBadRequest, fmt.Sprintf("Изменения поля %s не записываются", filter.Field))
+			return
+		}
+		if param := query.Get("limit"); param != "" {
+			limit, err := strconv.Atoi(param)
+			if err != nil || limit < 1 || limit > maxChanges {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Параметр limit должен быть числом от 1 до %d", maxChanges))
+				return
+			}
+			filter.Limit = limit
+		}
+
+		changes, err := store.Changes(currentUser(req), filter)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		if changes == nil {
+			changes = []TaskChange{}
+		}
+		writeJSON(res, http.StatusOK, map[string]any{
+			"changes": changes,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskChanges(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+	result, err := db.Exec(`INSERT INTO users (login, password_hash) VALUES ('anna', '')`)
+	assert.NoError(t, err)
+	anna, err := result.LastInsertId()
+	assert.NoError(t, err)
+
+	// Хранилище в памяти не знает логинов и оставляет Login пустым
+	logins := map[string]string{"memory": "", "sqlite": "anna"}
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		created, err := store.Create(anna, Task{Date: "20250106", Title: "Сдать отчёт", Count: "3"})
+		assert.NoError(t, err, name)
+		id := strconv.FormatInt(created, 10)
+
+		task := Task{ID: id, Date: "20250110", Title: "Сдать отчёт", Comment: "Квартальный", Count: "03"}
+		assert.NoError(t, store.Update(anna, task), name)
+		// Задача без изменений не добавляет записей
+		assert.NoError(t, store.Update(anna, task), name)
+		task.Date = "20250113"
+		assert.NoError(t, store.Update(anna, task), name)
+
+		changes, err := store.Changes(anna, ChangeFilter{TaskID: id})
+		assert.NoError(t, err, name)
+		var got [][3]string
+		for _, change := range changes {
+			got = append(got, [3]string{change.Field, change.Old, change.New})
+			assert.Equal(t, id, change.TaskID, name)
+			assert.Equal(t, strconv.FormatInt(anna, 10), change.UserID, name)
+			assert.Equal(t, logins[name], change.Login, name)
+			assert.NotEmpty(t, change.ChangedAt, name)
+		}
+		assert.Equal(t, [][3]string{
+			{"date", "20250110", "20250113"},
+			{"comment", "", "Квартальный"},
+			{"date", "20250106", "20250110"},
+		}, got, name)
+
+		changes, err = store.Changes(anna, ChangeFilter{Field: "date", Limit: 1})
+		assert.NoError(t, err, name)
+		if assert.Len(t, changes, 1, name) {
+			assert.Equal(t, "20250113", changes[0].New, name)
+		}
+		changes, err = store.Changes(anna+1, ChangeFilter{TaskID: id})
+		assert.NoError(t, err, name)
+		assert.Empty(t, changes, name)
+
+		// Отмена изменения и выполнения тоже записывается в журнал изменений
+		_, err = store.Undo(anna, 1)
+		assert.NoError(t, err, name)
+		task, err = store.Get(anna, id)
+		assert.NoError(t, err, name)
+		moved := task
+		moved.Date = "20250117"
+		assert.NoError(t, store.Complete(anna, moved, newCompletion(task, time.Now())), name)
+		_, err = store.Undo(anna, 1)
+		assert.NoError(t, err, name)
+
+		changes, err = store.Changes(anna, ChangeFilter{TaskID: id, Limit: 2})
+		assert.NoError(t, err, name)
+		got = nil
+		for _, change := range changes {
+			got = append(got, [3]string{change.Field, change.Old, change.New})
+			assert.Equal(t, strconv.FormatInt(anna, 10), change.UserID, name)
+		}
+		assert.Equal(t, [][3]string{
+			{"date", "20250117", "20250110"},
+			{"date", "20250113", "20250110"},
+		}, got, name)
+	}
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// tokenTTL совпадает со сроком жизни cookie, который выставляет страница входа
+const tokenTTL = 8 * time.Hour
+
+// sharedUserID — владелец задач, созданных без учётной записи: по общему паролю TODO_PASSWORD
+// или без аутентификации вовсе. Учётные записи пользователей начинаются с 1
+const sharedUserID int64 = 0
+
+type ctxKey int
+
+const userIDKey ctxKey = iota
+
+// randomKey подписывает токены, если не задан ни TODO_JWT_SECRET, ни TODO_PASSWORD.
+// Такие токены перестают действовать после перезапуска сервера
+var randomKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// passwordHash возвращает хеш пароля, который кладётся в токен:
+// после смены пароля старые токены перестают проходить проверку
+func passwordHash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// signingKey возвращает ключ подписи токенов: TODO_JWT_SECRET, а если он не задан — общий пароль
+func signingKey(config Config) []byte {
+	if config.JWTSecret != "" {
+		return []byte(config.JWTSecret)
+	}
+	if config.Password != "" {
+		return []byte(config.Password)
+	}
+	return randomKey
+}
+
+// newToken выпускает подписанный JWT для пользователя. secret — пароль, по которому
+// пользователь вошёл (для учётных записей — хеш пароля из базы)
+func newToken(config Config, userID int64, secret string) (string, error) {
+	claims := jwt.MapClaims{
+		"uid":  userID,
+		"hash": passwordHash(secret),
+		"exp":  time.Now().Add(tokenTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey(config))
+}
+
+// errInvalidToken — токен не прошёл проверку: подделан, истёк или выпущен до смены пароля
+var errInvalidToken = errors.New("недействительный токен")
+
+// credentialsError — ошибка в учётных данных запроса, в отличие от ошибок базы данных
+// она возвращается клиенту с кодом 401 или 400, а не 500
+type credentialsError string
+
+func (e credentialsError) Error() string {
+	return string(e)
+}
+
+// parseToken проверяет подпись, срок действия и хеш пароля в токене и возвращает id пользователя
+func (s *server) parseToken(tokenString string) (int64, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		return signingKey(s.config), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return 0, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errInvalidToken
+	}
+	hash, _ := claims["hash"].(string)
+	uid, _ := claims["uid"].(float64)
+	userID := int64(uid)
+
+	secret := s.config.Password
+	if userID != sharedUserID {
+		err := s.db.QueryRow(`SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&secret)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%w: пользователь не найден", errInvalidToken)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ошибка проверки пользователя: %v", err)
+		}
+	} else if s.config.Password == "" {
+		return 0, fmt.Errorf("%w: вход по общему паролю отключён", errInvalidToken)
+	}
+	if hash != passwordHash(secret) {
+		return 0, fmt.Errorf("%w: пароль изменён, требуется повторный вход", errInvalidToken)
+	}
+	return userID, nil
+}
+
+// authenticate определяет пользователя запроса. Без cookie "token" запрос относится
+// к общему списку задач, если общий пароль не задан. Недействительный токен (истёкший,
+// выпущенный до смены пароля или для удалённого пользователя) требует повторного входа:
+// иначе владелец учётной записи незаметно оказался бы в общем списке.
+// Ошибка возвращается, только если токен не удалось проверить из-за базы данных
+func (s *server) authenticate(req *http.Request) (int64, bool, error) {
+	cookie, err := req.Cookie("token")
+	if err != nil || cookie.Value == "" {
+		return sharedUserID, s.config.Password == "", nil
+	}
+	userID, err := s.parseToken(cookie.Value)
+	if errors.Is(err, errInvalidToken) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
+// auth пропускает запрос к next только для аутентифицированного пользователя
+// и кладёт его id в контекст запроса
+func (s *server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok, err := s.authenticate(req)
+		if err != nil {
+			writeError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeError(res, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+		next(res, req.WithContext(context.WithValue(req.Context(), userIDKey, userID)))
+	}
+}
+
+// currentUser возвращает id пользователя, определённый в auth
+func currentUser(req *http.Request) int64 {
+	userID, _ := req.Context().Value(userIDKey).(int64)
+	return userID
+}
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// signIn проверяет учётные данные и возвращает id пользователя и секрет для токена.
+// Без логина проверяется общий пароль TODO_PASSWORD
+func (s *server) signIn(cred credentials) (int64, string, error) {
+	if cred.Login == "" {
+		if s.config.Password == "" {
+			return 0, "", credentialsError("Общий пароль не задан, укажите логин")
+		}
+		if subtle.ConstantTimeCompare([]byte(cred.Password), []byte(s.config.Password)) != 1 {
+			return 0, "", credentialsError("Неверный пароль")
+		}
+		return sharedUserID, s.config.Password, nil
+	}
+
+	var userID int64
+	var hash string
+	err := s.db.QueryRow(`SELECT id, password_hash FROM users WHERE login = ?`, cred.Login).Scan(&userID, &hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, "", fmt.Errorf("ошибка поиска пользователя: %v", err)
+	}
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(hash), []byte(cred.Password)) != nil {
+		return 0, "", credentialsError("Неверный логин или пароль")
+	}
+	return userID, hash, nil
+}
+
+// signUp создаёт учётную запись и возвращает её id и секрет для токена
+func (s *server) signUp(cred credentials) (int64, string, error) {
+	cred.Login = strings.TrimSpace(cred.Login)
+	if cred.Login == "" || cred.Password == "" {
+		return 0, "", credentialsError("Логин и пароль являются обязательными")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(cred.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Занятый логин определяется по ограничению UNIQUE: отдельная проверка перед вставкой
+	// пропустила бы две одновременные регистрации одного логина
+	result, err := s.db.Exec(`INSERT INTO users (login, password_hash) VALUES (?, ?)`, cred.Login, string(hash))
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return 0, "", credentialsError(fmt.Sprintf("Пользователь %s уже существует", cred.Login))
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("ошибка создания пользователя: %v", err)
+	}
+	userID, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", fmt.Errorf("ошибка получения ID пользователя: %v", err)
+	}
+	return userID, string(hash), nil
+}
+
+// handleCredentials обслуживает /api/signin и /api/signup: проверяет или регистрирует
+// пользователя через check и возвращает токен для cookie "token".
+// Ошибка в учётных данных возвращается клиенту с кодом failStatus, остальные ошибки — с кодом 500
+func (s *server) handleCredentials(failStatus int, check func(credentials) (int64, string, error)) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		var cred credentials
+		if err := json.NewDecoder(req.Body).Decode(&cred); err != nil {
+			writeError(res, http.StatusBadRequest, "Неверный формат JSON")
+			return
+		}
+
+		userID, secret, err := check(cred)
+		var credErr credentialsError
+		if errors.As(err, &credErr) {
+			writeError(res, failStatus, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(res, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		token, err := newToken(s.config, userID, secret)
+		if err != nil {
+			writeError(res, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания токена: %v", err))
+			return
+		}
+
+		writeJSON(res, http.StatusOK, map[string]string{
+			"token": token,
+		})
+	}
+}
+
+func (s *server) handleSignIn(res http.ResponseWriter, req *http.Request) {
+	s.handleCredentials(http.StatusUnauthorized, s.signIn)(res, req)
+}
+
+func (s *server) handleSignUp(res http.ResponseWriter, req *http.Request) {
+	s.handleCredentials(http.StatusBadRequest, s.signUp)(res, req)
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthErrors(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := &server{db: db, store: newSQLiteStore(db), calendar: &calendar{}}
+	tasks := s.auth(handleGetTasks(s.store))
+	withCookie := func(handler http.HandlerFunc, method, target, token string, body any) int {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	code, m := serve(s.handleSignUp, http.MethodPost, "/api/signup", map[string]any{"login": "olga", "password": "pass"})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, http.StatusOK, withCookie(tasks, http.MethodGet, "/api/tasks", token, nil))
+
+	// Без cookie запрос попадает в общий список, а недействительный токен требует повторного входа,
+	// даже если общий пароль не задан
+	assert.Equal(t, http.StatusOK, withCookie(tasks, http.MethodGet, "/api/tasks", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, withCookie(tasks, http.MethodGet, "/api/tasks", "not-a-token", nil))
+
+	// Истёкший токен учётной записи не переключает пользователя на общий список
+	var hash string
+	assert.NoError(t, db.QueryRow(`SELECT password_hash FROM users WHERE login = 'olga'`).Scan(&hash))
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":  1,
+		"hash": passwordHash(hash),
+		"exp":  time.Now().Add(-time.Minute).Unix(),
+	}).SignedString(signingKey(s.config))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, withCookie(tasks, http.MethodGet, "/api/tasks", expired, nil))
+	assert.Equal(t, http.StatusUnauthorized, withCookie(s.auth(handleTask(s.store, s.calendar)), http.MethodPost, "/api/task",
+		expired, map[string]any{"title": "Чужая задача"}))
+
+	code, _ = serve(s.handleSignIn, http.MethodPost, "/api/signin", map[string]any{"login": "olga", "password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = serve(s.handleSignUp, http.MethodPost, "/api/signup", map[string]any{"login": "olga", "password": "pass"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Из одновременных регистраций одного логина проходит одна, остальные получают 400
+	codes := make(chan int, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := serve(s.handleSignUp, http.MethodPost, "/api/signup", map[string]any{"login": "petr", "password": "pass"})
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusBadRequest: cap(codes) - 1}, counts)
+
+	// Ошибка базы данных — это ошибка сервера, а не неверные учётные данные
+	db.Close()
+	assert.Equal(t, http.StatusInternalServerError, withCookie(tasks, http.MethodGet, "/api/tasks", token, nil))
+	code, _ = serve(s.handleSignIn, http.MethodPost, "/api/signin", map[string]any{"login": "olga", "password": "pass"})
+	assert.Equal(t, http.StatusInternalServerError, code)
+	code, _ = serve(s.handleSignUp, http.MethodPost, "/api/signup", map[string]any{"login": "ivan", "password": "pass"})
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// calendar — производственный календарь: суббота и воскресенье выходные,
+// кроме рабочих дней workdays, а праздники holidays — выходные в любой день недели
+type calendar struct {
+	holidays map[string]bool
+	workdays map[string]bool
+}
+
+// shiftLimit ограничивает поиск рабочего дня, чтобы календарь без рабочих дней не зацикливал перенос
+const shiftLimit = 366
+
+// isWorkday проверяет, является ли date рабочим днём
+func (c *calendar) isWorkday(date time.Time) bool {
+	key := date.Format("20060102")
+	if c.holidays[key] {
+		return false
+	}
+	if c.workdays[key] {
+		return true
+	}
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// addWorkdays возвращает дату через days рабочих дней после date
+func (c *calendar) addWorkdays(date time.Time, days int) (time.Time, error) {
+	for skipped := 0; days > 0; skipped++ {
+		if skipped > shiftLimit+days {
+			return time.Time{}, fmt.Errorf("в календаре нет рабочих дней")
+		}
+		date = date.AddDate(0, 0, 1)
+		if c.isWorkday(date) {
+			days--
+		}
+	}
+	return date, nil
+}
+
+// shift переносит date с выходного или праздника на ближайший рабочий день:
+// следующий для "next", предыдущий для "prev". Пустое правило дату не меняет
+func (c *calendar) shift(date time.Time, policy string) (time.Time, error) {
+	step := 0
+	switch policy {
+	case "":
+		return date, nil
+	case "next":
+		step = 1
+	case "prev":
+		step = -1
+	default:
+		return time.Time{}, fmt.Errorf("неизвестное правило переноса: %s, ожидается next или prev", policy)
+	}
+	for i := 0; i < shiftLimit; i++ {
+		if c.isWorkday(date) {
+			return date, nil
+		}
+		date = date.AddDate(0, 0, step)
+	}
+	return time.Time{}, fmt.Errorf("в календаре нет рабочих дней")
+}
+
+// loadCalendar читает праздники из файла path. Файл .ics читается как iCalendar:
+// каждое событие VEVENT — праздник с DTSTART по DTEND. Остальные файлы читаются как JSON
+// вида {"holidays": ["20250101", ...], "workdays": ["20251101", ...]}, где workdays —
+// перенесённые рабочие субботы и воскресенья. Пустой path — календарь только с выходными
+func loadCalendar(path string) (*calendar, error) {
+	c := &calendar{holidays: map[string]bool{}, workdays: map[string]bool{}}
+	if path == "" {
+		return c, nil
+	}
+
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		err = c.loadICS(path)
+	} else {
+		err = c.loadJSON(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения календаря %s: %v", path, err)
+	}
+	return c, nil
+}
+
+func (c *calendar) loadJSON(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Holidays []string `json:"holidays"`
+		Workdays []string `json:"workdays"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for _, list := range []struct {
+		dates []string
+		set   map[string]bool
+	}{{file.Holidays, c.holidays}, {file.Workdays, c.workdays}} {
+		for _, date := range list.dates {
+			if _, err := time.Parse("20060102", date); err != nil {
+				return fmt.Errorf("неправильный формат даты, ожидается YYYYMMDD: %s", date)
+			}
+			list.set[date] = true
+		}
+	}
+	return nil
+}
+
+func (c *calendar) loadICS(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// icsDate читает дату из строки вида DTSTART;VALUE=DATE:20250101
+	icsDate := func(line string) (time.Time, error) {
+		value := line[strings.Index(line, ":")+1:]
+		if len(value) < 8 {
+			return time.Time{}, fmt.Errorf("неправильная дата: %s", line)
+		}
+		return time.Parse("20060102", value[:8])
+	}
+
+	var inEvent bool
+	var start, end time.Time
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name := strings.ToUpper(line)
+		if i := strings.IndexAny(name, ";:"); i >= 0 {
+			name = name[:i]
+		}
+
+		switch {
+		case strings.EqualFold(line, "BEGIN:VEVENT"):
+			inEvent, start, end = true, time.Time{}, time.Time{}
+		case !inEvent:
+			// Даты вне событий (например, в VTIMEZONE) праздниками не являются
+		case name == "DTSTART":
+			if start, err = icsDate(line); err != nil {
+				return err
+			}
+		case name == "DTEND":
+			if end, err = icsDate(line); err != nil {
+				return err
+			}
+		case strings.EqualFold(line, "END:VEVENT"):
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// DTEND не входит в событие; без него событие длится один день
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+				c.holidays[date.Format("20060102")] = true
+			}
+		}
+	}
+	return scanner.Err()
+}
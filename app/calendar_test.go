@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCalendar(t *testing.T) {
+	dir := t.TempDir()
+	date := func(s string) time.Time {
+		d, err := time.Parse("20060102", s)
+		assert.NoError(t, err)
+		return d
+	}
+
+	jsonFile := filepath.Join(dir, "holidays.json")
+	assert.NoError(t, os.WriteFile(jsonFile, []byte(`{
+		"holidays": ["20250101", "20250102", "20250103", "20250106", "20250107", "20250108"],
+		"workdays": ["20251101"]
+	}`), 0644))
+	c, err := loadCalendar(jsonFile)
+	assert.NoError(t, err)
+	assert.False(t, c.isWorkday(date("20250102")))
+	assert.True(t, c.isWorkday(date("20250109")))
+	assert.True(t, c.isWorkday(date("20251101")), "перенесённая рабочая суббота")
+	assert.False(t, c.isWorkday(date("20251102")))
+
+	next, err := c.addWorkdays(date("20241231"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, date("20250109"), next)
+	shifted, err := c.shift(date("20250101"), "prev")
+	assert.NoError(t, err)
+	assert.Equal(t, date("20241231"), shifted)
+	shifted, err = c.shift(date("20250101"), "next")
+	assert.NoError(t, err)
+	assert.Equal(t, date("20250109"), shifted)
+	_, err = c.shift(date("20250101"), "later")
+	assert.Error(t, err)
+
+	icsFile := filepath.Join(dir, "holidays.ics")
+	assert.NoError(t, os.WriteFile(icsFile, []byte("BEGIN:VCALENDAR\r\n"+
+		"BEGIN:VTIMEZONE\r\nBEGIN:STANDARD\r\nDTSTART:19701025T030000\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250501\r\nSUMMARY:Праздник весны и труда\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250508\r\nDTEND;VALUE=DATE:20250510\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n"), 0644))
+	c, err = loadCalendar(icsFile)
+	assert.NoError(t, err)
+	assert.False(t, c.isWorkday(date("20250501")))
+	assert.False(t, c.isWorkday(date("20250508")))
+	assert.False(t, c.isWorkday(date("20250509")))
+	assert.True(t, c.isWorkday(date("20250512")))
+	assert.Len(t, c.holidays, 3)
+
+	assert.NoError(t, os.WriteFile(jsonFile, []byte(`{"holidays": ["2025-01-01"]}`), 0644))
+	_, err = loadCalendar(jsonFile)
+	assert.Error(t, err)
+	_, err = loadCalendar(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestServerCalendars(t *testing.T) {
+	dir := t.TempDir()
+	holidays := filepath.Join(dir, "holidays.json")
+	assert.NoError(t, os.WriteFile(holidays, []byte(`{"holidays": ["20250102", "20250103"]}`), 0644))
+
+	// У каждого сервера свой календарь, второй сервер не подменяет праздники первого
+	var handlers []http.Handler
+	for i, file := range []string{holidays, ""} {
+		handler, closeServer, err := NewServer(Config{
+			DbFilePath:   filepath.Join(dir, fmt.Sprintf("scheduler%d.db", i)),
+			HolidaysFile: file,
+		})
+		assert.NoError(t, err)
+		defer closeServer()
+		handlers = append(handlers, handler)
+	}
+	for i, want := range []string{"20250106", "20250102"} {
+		rec := httptest.NewRecorder()
+		handlers[i].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nextdate?now=20250101&date=20250101&repeat=b%201", nil))
+		assert.Equal(t, want, rec.Body.String())
+	}
+}
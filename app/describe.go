@@ -0,0 +1,399 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Языки описания правил повторения
+const (
+	langRU = "ru"
+	langEN = "en"
+)
+
+// requestLang выбирает язык описаний: параметр lang, затем заголовок Accept-Language.
+// По умолчанию описания на русском
+func requestLang(lang, acceptLanguage string) string {
+	if lang == "" {
+		lang = acceptLanguage
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(lang)), langEN) {
+		return langEN
+	}
+	return langRU
+}
+
+// describeRule описывает правило повторения фразой на языке lang, например
+// «10 и 17 января, августа и декабря» для m 10,17 12,8,1. Пустое правило описывается пустой строкой
+func describeRule(rule, lang string) (string, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return "", nil
+	}
+
+	var r rrule
+	if !isRRule(rule) {
+		// Рабочие дни и повторения внутри дня не выражаются в RRULE, описываем их отдельно
+		parsed, err := parseRule(rule)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case parsed.kind == "b" && lang == langEN:
+			return enEvery(parsed.days, "working day", "working days"), nil
+		case parsed.kind == "b":
+			return ruEvery(parsed.days, ruWorkingDay), nil
+		case parsed.intraday():
+			return describeIntraday(parsed, lang), nil
+		}
+	}
+
+	converted, err := toRRule(rule)
+	if err != nil {
+		return "", err
+	}
+	if r, err = parseRRule(converted); err != nil {
+		return "", err
+	}
+	if lang == langEN {
+		return describeEN(r), nil
+	}
+	return describeRU(r), nil
+}
+
+// ruNoun — формы существительного для числительных: 1 день, 2 дня, 5 дней.
+// every — «каждый день» или «каждую неделю» для интервала 1
+type ruNoun struct {
+	one, few, many string
+	every          string
+}
+
+var (
+	ruDay        = ruNoun{"день", "дня", "дней", "каждый день"}
+	ruWorkingDay = ruNoun{"рабочий день", "рабочих дня", "рабочих дней", "каждый рабочий день"}
+	ruWeek       = ruNoun{"неделю", "недели", "недель", "каждую неделю"}
+	ruMonth      = ruNoun{"месяц", "месяца", "месяцев", "каждый месяц"}
+	ruYear       = ruNoun{"год", "года", "лет", "каждый год"}
+	ruHour       = ruNoun{"час", "часа", "часов", "каждый час"}
+	ruMinute     = ruNoun{"минуту", "минуты", "минут", "каждую минуту"}
+	ruTimes      = ruNoun{"раз", "раза", "раз", ""}
+)
+
+// plural выбирает форму существительного для числа n
+func (w ruNoun) plural(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return w.one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return w.few
+	default:
+		return w.many
+	}
+}
+
+// ruEvery возвращает «каждый день», «каждые 3 дня» или «каждый 21 день»
+func ruEvery(n int, w ruNoun) string {
+	if n == 1 {
+		return w.every
+	}
+	if w.plural(n) == w.one {
+		return fmt.Sprintf("%s %d %s", strings.Fields(w.every)[0], n, w.one)
+	}
+	return fmt.Sprintf("каждые %d %s", n, w.plural(n))
+}
+
+// Названия дней недели в порядке time.Weekday: во множественном числе дательного падежа
+// («по понедельникам»), в винительном падеже («в понедельник») и род для порядкового числительного
+var (
+	ruWeekdaysDative = []string{"воскресеньям", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам"}
+	ruWeekdaysAcc    = []string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}
+	ruWeekdaysGender = []int{2, 0, 0, 1, 0, 1, 1} // 0 — мужской, 1 — женский, 2 — средний
+)
+
+// Порядковые числительные в винительном падеже для мужского, женского и среднего рода
+var ruOrdinals = map[int][3]string{
+	1:  {"первый", "первую", "первое"},
+	2:  {"второй", "вторую", "второе"},
+	3:  {"третий", "третью", "третье"},
+	4:  {"четвёртый", "четвёртую", "четвёртое"},
+	5:  {"пятый", "пятую", "пятое"},
+	-1: {"последний", "последнюю", "последнее"},
+	-2: {"предпоследний", "предпоследнюю", "предпоследнее"},
+}
+
+// Названия месяцев в родительном («10 января») и предложном («в январе») падежах
+var (
+	ruMonthsGenitive      = []string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrepositional = []string{"январе", "феврале", "марте", "апреле", "мае", "июне", "июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+)
+
+// ruOrdinal возвращает порядковое числительное рода gender: «вторую», «последний», «7-й с конца»
+func ruOrdinal(n, gender int) string {
+	if words, ok := ruOrdinals[n]; ok {
+		return words[gender]
+	}
+	ending := []string{"-й", "-ю", "-е"}[gender]
+	if n < 0 {
+		return strconv.Itoa(-n) + ending + " с конца"
+	}
+	return strconv.Itoa(n) + ending
+}
+
+// ruIn возвращает предлог «в» или «во» перед словом: «во вторник», «в среду»
+func ruIn(word string) string {
+	if strings.HasPrefix(word, "вт") {
+		return "во " + word
+	}
+	return "в " + word
+}
+
+// joinWords соединяет слова через запятую, а последнее — через and: «a, b и c»
+func joinWords(words []string, and string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " " + and + " " + words[len(words)-1]
+}
+
+// sortedMonths возвращает месяцы правила по порядку
+func sortedMonths(r rrule) []int {
+	months := slices.Clone(r.byMonth)
+	slices.Sort(months)
+	return slices.Compact(months)
+}
+
+// splitMonthDays разделяет дни месяца на дни от начала по возрастанию и дни от конца месяца
+func splitMonthDays(r rrule) (positive, negative []int) {
+	for _, day := range r.byMonthDay {
+		if day > 0 {
+			positive = append(positive, day)
+		} else {
+			negative = append(negative, day)
+		}
+	}
+	slices.Sort(positive)
+	slices.Sort(negative)
+	slices.Reverse(negative)
+	return slices.Compact(positive), slices.Compact(negative)
+}
+
+// ruPeriods — единицы частоты FREQ
+var ruPeriods = map[string]ruNoun{"DAILY": ruDay, "WEEKLY": ruWeek, "MONTHLY": ruMonth, "YEARLY": ruYear}
+
+func describeRU(r rrule) string {
+	var segments []string
+	hasFilters := len(r.byDay)+len(r.byMonthDay)+len(r.byMonth) > 0
+	if r.interval > 1 || !hasFilters || r.freq == "YEARLY" {
+		segments = append(segments, ruEvery(r.interval, ruPeriods[r.freq]))
+	}
+
+	// Дни: «по понедельникам», «во второй вторник», «10 и 17», «в последний день»
+	var plain, ordinal []string
+	for _, wd := range r.byDay {
+		if wd.ord == 0 {
+			plain = append(plain, ruWeekdaysDative[wd.day])
+		} else {
+			ordinal = append(ordinal, ruIn(ruOrdinal(wd.ord, ruWeekdaysGender[wd.day])+" "+ruWeekdaysAcc[wd.day]))
+		}
+	}
+	positive, negative := splitMonthDays(r)
+	var days []string
+	if len(plain) > 0 {
+		days = append(days, "по "+joinWords(plain, "и"))
+	}
+	days = append(days, ordinal...)
+	if len(positive) > 0 {
+		numbers := make([]string, len(positive))
+		for i, day := range positive {
+			numbers[i] = strconv.Itoa(day)
+		}
+		days = append(days, joinWords(numbers, "и"))
+	}
+	for _, day := range negative {
+		days = append(days, ruIn(ruOrdinal(day, 0)+" день"))
+	}
+	phrase := joinWords(days, "и")
+
+	// Месяцы или период, к которому относятся дни
+	months := sortedMonths(r)
+	inPeriod := len(ordinal)+len(negative) > 0
+	switch {
+	case len(months) > 0 && (inPeriod || len(positive) > 0):
+		names := make([]string, len(months))
+		for i, month := range months {
+			names[i] = ruMonthsGenitive[month-1]
+		}
+		phrase += " " + joinWords(names, "и")
+	case len(months) > 0:
+		names := make([]string, len(months))
+		for i, month := range months {
+			names[i] = ruMonthsPrepositional[month-1]
+		}
+		phrase = strings.TrimSpace(phrase + " в " + joinWords(names, "и"))
+	case inPeriod && r.freq == "YEARLY":
+		phrase += " года"
+	case inPeriod:
+		phrase += " месяца"
+	case len(positive) > 0 && len(segments) == 0:
+		phrase += " числа каждого месяца"
+	case len(positive) > 0:
+		phrase += " числа"
+	}
+	if phrase != "" {
+		segments = append(segments, phrase)
+	}
+
+	if r.count > 0 {
+		segments = append(segments, fmt.Sprintf("%d %s", r.count, ruTimes.plural(r.count)))
+	}
+	if !r.until.IsZero() {
+		segments = append(segments, "до "+r.until.Format("02.01.2006"))
+	}
+	return strings.Join(segments, ", ")
+}
+
+// describeIntraday описывает правило h или n: «каждые 2 часа с 09:00 до 18:00 по понедельникам»
+func describeIntraday(r repeatRule, lang string) string {
+	count, ruUnit, enUnit := int(r.interval/time.Minute), ruMinute, [2]string{"minute", "minutes"}
+	if r.kind == "h" {
+		count, ruUnit, enUnit = int(r.interval/time.Hour), ruHour, [2]string{"hour", "hours"}
+	}
+	// Дни недели по порядку с понедельника
+	weekdays := slices.Clone(r.weekdays)
+	slices.SortFunc(weekdays, func(a, b rruleWeekday) int {
+		return (int(a.day)+6)%7 - (int(b.day)+6)%7
+	})
+	days := make([]string, len(weekdays))
+
+	if lang == langEN {
+		phrase := enEvery(count, enUnit[0], enUnit[1])
+		if r.hasWindow {
+			phrase += fmt.Sprintf(" from %s to %s", r.windowFrom.Format("15:04"), r.windowTo.Format("15:04"))
+		}
+		for i, wd := range weekdays {
+			days[i] = wd.day.String() + "s"
+		}
+		if len(days) > 0 {
+			phrase += " on " + joinWords(days, "and")
+		}
+		return phrase
+	}
+
+	phrase := ruEvery(count, ruUnit)
+	if r.hasWindow {
+		phrase += fmt.Sprintf(" с %s до %s", r.windowFrom.Format("15:04"), r.windowTo.Format("15:04"))
+	}
+	for i, wd := range weekdays {
+		days[i] = ruWeekdaysDative[wd.day]
+	}
+	if len(days) > 0 {
+		phrase += " по " + joinWords(days, "и")
+	}
+	return phrase
+}
+
+// enEvery возвращает «every day» или «every 3 days»
+func enEvery(n int, one, many string) string {
+	if n == 1 {
+		return "every " + one
+	}
+	return fmt.Sprintf("every %d %s", n, many)
+}
+
+// enOrdinal возвращает «1st», «22nd», «second», «last» или «3rd to last»
+func enOrdinal(n int, words bool) string {
+	names := map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth", -1: "last", -2: "second to last"}
+	if name, ok := names[n]; ok && (words || n < 0) {
+		return name
+	}
+	if n < 0 {
+		return enOrdinal(-n, false) + " to last"
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+var enPeriods = map[string][2]string{
+	"DAILY":   {"day", "days"},
+	"WEEKLY":  {"week", "weeks"},
+	"MONTHLY": {"month", "months"},
+	"YEARLY":  {"year", "years"},
+}
+
+func describeEN(r rrule) string {
+	var segments []string
+	hasFilters := len(r.byDay)+len(r.byMonthDay)+len(r.byMonth) > 0
+	if r.interval > 1 || !hasFilters || r.freq == "YEARLY" {
+		period := enPeriods[r.freq]
+		segments = append(segments, enEvery(r.interval, period[0], period[1]))
+	}
+
+	var plain, ordinal []string
+	for _, wd := range r.byDay {
+		if wd.ord == 0 {
+			plain = append(plain, wd.day.String()+"s")
+		} else {
+			ordinal = append(ordinal, enOrdinal(wd.ord, true)+" "+wd.day.String())
+		}
+	}
+	positive, negative := splitMonthDays(r)
+	for _, day := range positive {
+		ordinal = append(ordinal, enOrdinal(day, false))
+	}
+	for _, day := range negative {
+		ordinal = append(ordinal, enOrdinal(day, true)+" day")
+	}
+
+	var days []string
+	if len(plain) > 0 {
+		days = append(days, "on "+joinWords(plain, "and"))
+	}
+	if len(ordinal) > 0 {
+		days = append(days, "on the "+joinWords(ordinal, "and"))
+	}
+	phrase := joinWords(days, "and")
+
+	months := sortedMonths(r)
+	names := make([]string, len(months))
+	for i, month := range months {
+		names[i] = time.Month(month).String()
+	}
+	switch {
+	case len(months) > 0 && len(ordinal) > 0:
+		phrase += " of " + joinWords(names, "and")
+	case len(months) > 0:
+		phrase = strings.TrimSpace(phrase + " in " + joinWords(names, "and"))
+	case len(ordinal) > 0 && r.freq == "YEARLY":
+		phrase += " of the year"
+	case len(ordinal) > 0 && len(segments) == 0:
+		phrase += " of every month"
+	case len(ordinal) > 0:
+		phrase += " of the month"
+	}
+	if phrase != "" {
+		segments = append(segments, phrase)
+	}
+
+	if r.count == 1 {
+		segments = append(segments, "once")
+	} else if r.count > 1 {
+		segments = append(segments, fmt.Sprintf("%d times", r.count))
+	}
+	if !r.until.IsZero() {
+		segments = append(segments, "until "+r.until.Format("2006-01-02"))
+	}
+	return strings.Join(segments, ", ")
+}
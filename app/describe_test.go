@@ -0,0 +1,67 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeRule(t *testing.T) {
+	tbl := []struct {
+		rule string
+		ru   string
+		en   string
+	}{
+		{"", "", ""},
+		{"d 1", "каждый день", "every day"},
+		{"d 3", "каждые 3 дня", "every 3 days"},
+		{"d 7", "каждые 7 дней", "every 7 days"},
+		{"d 21", "каждый 21 день", "every 21 days"},
+		{"b 1", "каждый рабочий день", "every working day"},
+		{"b 5", "каждые 5 рабочих дней", "every 5 working days"},
+		{"h 1", "каждый час", "every hour"},
+		{"h 2 09:00-18:00 5,1", "каждые 2 часа с 09:00 до 18:00 по понедельникам и пятницам",
+			"every 2 hours from 09:00 to 18:00 on Mondays and Fridays"},
+		{"n 21", "каждую 21 минуту", "every 21 minutes"},
+		{"n 45 1,2,3,4,5", "каждые 45 минут по понедельникам, вторникам, средам, четвергам и пятницам",
+			"every 45 minutes on Mondays, Tuesdays, Wednesdays, Thursdays and Fridays"},
+		{"y", "каждый год", "every year"},
+		{"w 1,3,5", "по понедельникам, средам и пятницам", "on Mondays, Wednesdays and Fridays"},
+		{"w 7", "по воскресеньям", "on Sundays"},
+		{"w 2#2", "во второй вторник месяца", "on the second Tuesday of every month"},
+		{"w 5#-1 1,7", "в последнюю пятницу января и июля", "on the last Friday of January and July"},
+		{"w 3#1,7#-2", "в первую среду и в предпоследнее воскресенье месяца",
+			"on the first Wednesday and second to last Sunday of every month"},
+		{"w 1 6", "по понедельникам в июне", "on Mondays in June"},
+		{"m 10,17 12,8,1", "10 и 17 января, августа и декабря", "on the 10th and 17th of January, August and December"},
+		{"m 3", "3 числа каждого месяца", "on the 3rd of every month"},
+		{"m -1", "в последний день месяца", "on the last day of every month"},
+		{"m -1,15", "15 и в последний день месяца", "on the 15th and last day of every month"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "каждые 2 недели, по понедельникам и пятницам",
+			"every 2 weeks, on Mondays and Fridays"},
+		{"RRULE:FREQ=MONTHLY;INTERVAL=3;BYDAY=2TU", "каждые 3 месяца, во второй вторник месяца",
+			"every 3 months, on the second Tuesday of the month"},
+		{"RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "каждый год, 29 февраля", "every year, on the 29th of February"},
+		{"RRULE:FREQ=YEARLY;BYDAY=10MO", "каждый год, в 10-й понедельник года", "every year, on the 10th Monday of the year"},
+		{"RRULE:FREQ=DAILY;COUNT=5", "каждый день, 5 раз", "every day, 5 times"},
+		{"RRULE:FREQ=WEEKLY;UNTIL=20251231", "каждую неделю, до 31.12.2025", "every week, until 2025-12-31"},
+		{"RRULE:FREQ=MONTHLY;COUNT=1", "каждый месяц, 1 раз", "every month, once"},
+	}
+	for _, v := range tbl {
+		ru, err := describeRule(v.rule, langRU)
+		assert.NoError(t, err, v.rule)
+		assert.Equal(t, v.ru, ru, v.rule)
+		en, err := describeRule(v.rule, langEN)
+		assert.NoError(t, err, v.rule)
+		assert.Equal(t, v.en, en, v.rule)
+	}
+
+	for _, rule := range []string{"k 34", "d", "b 0", "RRULE:FREQ=HOURLY"} {
+		_, err := describeRule(rule, langRU)
+		assert.Error(t, err, rule)
+	}
+
+	assert.Equal(t, langEN, requestLang("", "en-US,en;q=0.9"))
+	assert.Equal(t, langRU, requestLang("ru", "en-US"))
+	assert.Equal(t, langRU, requestLang("", ""))
+}
@@ -0,0 +1,988 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Task struct {
+	ID      string `json:"id"`
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+	Repeat  string `json:"repeat"`
+	Count   string `json:"count,omitempty"`   // сколько повторений осталось, включая текущее
+	Until   string `json:"until,omitempty"`   // последняя допустимая дата повторения, YYYYMMDD
+	Exdates string `json:"exdates,omitempty"` // даты-исключения YYYYMMDD через запятую
+	Shift   string `json:"shift,omitempty"`   // перенос с выходных и праздников: next или prev
+	Time    string `json:"time,omitempty"`    // время выполнения HH:MM в часовом поясе задачи
+	TZ      string `json:"tz,omitempty"`      // часовой пояс IANA, по умолчанию пояс сервера
+	Mode    string `json:"mode,omitempty"`    // отсчёт повторений: schedule (по умолчанию) или completion
+	// RuleDate — дата повторения по правилу, если дата задачи перенесена с выходного по shift.
+	// Следующие повторения отсчитываются от неё, чтобы перенос не сдвигал расписание
+	RuleDate string `json:"rule_date,omitempty"`
+
+	// RepeatText — описание правила повторения словами, только в ответах GET
+	RepeatText string `json:"repeat_text,omitempty"`
+	// Due — момент выполнения задачи со временем в формате RFC 3339, только в ответах GET
+	Due string `json:"due,omitempty"`
+	// Status — состояние задачи: active, done или archived, и момент перехода в done или archived.
+	// Задаются хранилищем и в запросах не учитываются
+	Status     string `json:"status,omitempty"`
+	ArchivedAt string `json:"archived_at,omitempty"`
+	// DeletedAt — момент переноса задачи в корзину, задаётся хранилищем
+	DeletedAt string `json:"deleted_at,omitempty"`
+
+	// moment — момент выполнения, вычисленный при переносе задачи по правилу h или n. В отличие
+	// от Date и Time он помнит смещение зоны: время, которое повторяется при переводе часов назад,
+	// по одной записи HH:MM всегда читалось бы как первое. Не сохраняется и в JSON не попадает
+	moment time.Time
+}
+
+// UnmarshalJSON читает задачу из JSON, принимая число повторений count
+// и строкой ("3"), и числом (3)
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	var v struct {
+		plain
+		Count json.RawMessage `json:"count"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = Task(v.plain)
+
+	switch {
+	case len(v.Count) == 0 || string(v.Count) == "null":
+	case v.Count[0] == '"':
+		var s string
+		if err := json.Unmarshal(v.Count, &s); err != nil {
+			return err
+		}
+		t.Count = s
+	default:
+		var count json.Number
+		if err := json.Unmarshal(v.Count, &count); err != nil {
+			return fmt.Errorf("число повторений должно быть строкой или числом: %s", v.Count)
+		}
+		t.Count = count.String()
+	}
+	return nil
+}
+
+// withRepeatText добавляет к задаче описание правила повторения на языке запроса
+func withRepeatText(req *http.Request, task Task) Task {
+	lang := requestLang(req.URL.Query().Get("lang"), req.Header.Get("Accept-Language"))
+	task.RepeatText, _ = describeRule(task.Repeat, lang)
+	return task
+}
+
+// writeJSON отправляет ответ v в формате JSON с кодом status
+func writeJSON(res http.ResponseWriter, status int, v any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
+
+// writeError отправляет ошибку в формате {"error": "..."}
+func writeError(res http.ResponseWriter, status int, message string) {
+	writeJSON(res, status, map[string]string{
+		"error": message,
+	})
+}
+
+// writeStoreError отправляет ошибку хранилища: 404 для отсутствующей задачи, иначе 500
+func writeStoreError(res http.ResponseWriter, err error) {
+	if errors.Is(err, ErrTaskNotFound) {
+		writeError(res, http.StatusNotFound, "Задача не найдена")
+		return
+	}
+	writeError(res, http.StatusInternalServerError, err.Error())
+}
+
+// checkTaskUpdate проверяет изменённую задачу и приводит её дату к формату хранения
+func checkTaskUpdate(cal *calendar, task Task) (Task, error) {
+	if err := checkDueTime(task); err != nil {
+		return Task{}, err
+	}
+	// Сегодняшний день определяется в часовом поясе задачи
+	now, err := taskToday(task)
+	if err != nil {
+		return Task{}, err
+	}
+
+	// Парсим дату задачи
+	var taskDate time.Time
+
+	if task.Date != "" {
+		// Если дата указана, пытаемся её распарсить
+		taskDate, err = time.Parse("20060102", task.Date)
+		if err != nil {
+			return Task{}, fmt.Errorf("неправильный формат даты, ожидается YYYYMMDD: %v", err)
+		}
+	} else {
+		// Если дата не указана, используем текущую
+		taskDate = now
+	}
+
+	// Проверяем правило повторения
+	if task.Repeat != "" {
+		_, err := nextDate(cal, now, taskDate, task.Repeat)
+		if err != nil {
+			return Task{}, fmt.Errorf("не удалось вычислить следующую дату выполнения: %v", err)
+		}
+	}
+	task, err = checkRepeatLimits(cal, task, taskDate)
+	if err != nil {
+		return Task{}, err
+	}
+
+	task.Date = taskDate.Format("20060102")
+	return task, nil
+}
+
+// Режимы отсчёта повторений: от даты задачи по расписанию или от дня выполнения
+const (
+	modeSchedule   = "schedule"
+	modeCompletion = "completion"
+)
+
+// checkRepeatLimits проверяет число повторений count, последнюю дату until, даты-исключения
+// exdates, перенос с выходных shift и режим повторения mode: они задаются только вместе с правилом,
+// а дата задачи не может быть позже until. Даты-исключения упорядочиваются и очищаются от повторов
+func checkRepeatLimits(cal *calendar, task Task, taskDate time.Time) (Task, error) {
+	if task.Count == "" && task.Until == "" && task.Exdates == "" && task.Shift == "" && task.Mode == "" {
+		task.RuleDate = ""
+		return task, nil
+	}
+	if task.Repeat == "" {
+		return Task{}, fmt.Errorf("поля 'count', 'until', 'exdates', 'shift' и 'mode' задаются только вместе с правилом повторения")
+	}
+	if task.Mode != "" && task.Mode != modeSchedule && task.Mode != modeCompletion {
+		return Task{}, fmt.Errorf("неизвестный режим повторения: %s, ожидается %s или %s", task.Mode, modeSchedule, modeCompletion)
+	}
+	if _, err := cal.shift(taskDate, task.Shift); err != nil {
+		return Task{}, err
+	}
+	if task.Shift != "" && isIntradayRule(task.Repeat) {
+		return Task{}, fmt.Errorf("перенос с выходных не применяется к правилам h и n, укажите дни недели в правиле")
+	}
+	if task.Count != "" {
+		if count, err := strconv.Atoi(task.Count); err != nil || count < 1 {
+			return Task{}, fmt.Errorf("число повторений должно быть положительным целым: %s", task.Count)
+		}
+	}
+	if task.Until != "" {
+		until, err := time.Parse("20060102", task.Until)
+		if err != nil {
+			return Task{}, fmt.Errorf("неправильный формат даты окончания повторений, ожидается YYYYMMDD: %v", err)
+		}
+		if taskDate.After(until) {
+			return Task{}, fmt.Errorf("дата задачи позже даты окончания повторений %s", task.Until)
+		}
+	}
+	if task.Exdates != "" {
+		var dates []string
+		for _, item := range strings.Split(task.Exdates, ",") {
+			item = strings.TrimSpace(item)
+			if _, err := time.Parse("20060102", item); err != nil {
+				return Task{}, fmt.Errorf("неправильный формат даты-исключения, ожидается YYYYMMDD: %s", item)
+			}
+			dates = append(dates, item)
+		}
+		slices.Sort(dates)
+		dates = slices.Compact(dates)
+		task.Exdates = strings.Join(dates, ",")
+	}
+	// Дата по правилу сохраняется, только если перенос с неё по shift даёт дату задачи:
+	// дату, изменённую вручную, правило отсчитывает от неё самой
+	if task.RuleDate != "" {
+		ruleDate, err := time.Parse("20060102", task.RuleDate)
+		if err != nil {
+			return Task{}, fmt.Errorf("неправильный формат даты по правилу, ожидается YYYYMMDD: %v", err)
+		}
+		if shifted, err := cal.shift(ruleDate, task.Shift); err != nil || !shifted.Equal(taskDate) || shifted.Equal(ruleDate) {
+			task.RuleDate = ""
+		}
+	}
+	return task, nil
+}
+
+// isExdate проверяет, входит ли дата в даты-исключения задачи
+func isExdate(task Task, date time.Time) bool {
+	return task.Exdates != "" && strings.Contains(","+task.Exdates+",", ","+date.Format("20060102")+",")
+}
+
+// nextOccurrence переносит периодическую задачу на ближайшее повторение после момента now:
+// задачу с правилом по дням — на дату после сегодняшней в её часовом поясе, сдвигая повторение
+// с выходных по правилу shift, задачу с правилом h или n — на момент после now.
+// Повторения отсчитываются от даты по правилу до переноса, а не от перенесённой даты задачи.
+// Даты-исключения пропускаются. Возвращает errNoMoreOccurrences, если повторения закончились
+// по правилу или дате until
+func nextOccurrence(cal *calendar, task Task, now time.Time) (Task, error) {
+	base := task.Date
+	if task.RuleDate != "" {
+		base = task.RuleDate
+	}
+	taskDate, err := time.Parse("20060102", base)
+	if err != nil {
+		return Task{}, fmt.Errorf("ошибка парсинга даты: %v", err)
+	}
+	loc, err := taskLocation(task)
+	if err != nil {
+		return Task{}, err
+	}
+	if r, err := parseRule(task.Repeat); err == nil && r.intraday() {
+		return nextIntradayOccurrence(task, r, now, loc)
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	next, shifted, err := nextShiftedDate(cal, today, taskDate, task.Repeat, task.Shift, func(date time.Time) bool {
+		return isExdate(task, date)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	if task.Until != "" && shifted.Format("20060102") > task.Until {
+		return Task{}, errNoMoreOccurrences
+	}
+	task.Repeat, err = advanceRepeat(task.Repeat, taskDate, next)
+	if err != nil {
+		return Task{}, err
+	}
+	task.Date = shifted.Format("20060102")
+	task.RuleDate = ""
+	if !shifted.Equal(next) {
+		task.RuleDate = next.Format("20060102")
+	}
+	return task, nil
+}
+
+// intradayScanLimit ограничивает перебор повторений правила h или n, пропущенных с даты задачи
+const intradayScanLimit = 1000000
+
+// nextIntradayOccurrence переносит задачу с правилом h или n на ближайшее срабатывание
+// после момента now, пропуская дни из дат-исключений
+func nextIntradayOccurrence(task Task, r repeatRule, now time.Time, loc *time.Location) (Task, error) {
+	current, err := taskMoment(task, loc)
+	if err != nil {
+		return Task{}, err
+	}
+	next := r.stepAt(current, loc)
+	for i := 0; !next.After(now) || isExdate(task, next); i++ {
+		if i == intradayScanLimit {
+			return Task{}, fmt.Errorf("слишком много пропущенных повторений с %s", task.Date)
+		}
+		next = r.stepAt(next, loc)
+	}
+	if task.Until != "" && next.Format("20060102") > task.Until {
+		return Task{}, errNoMoreOccurrences
+	}
+	task.Date = next.Format("20060102")
+	task.Time = next.Format("15:04")
+	task.moment = next
+	return task, nil
+}
+
+// taskMoment возвращает момент выполнения задачи в часовом поясе loc:
+// дата и время задачи или начало дня для задачи без времени. Момент, вычисленный при переносе
+// по правилу h или n, берётся как есть, если дата и время задачи с тех пор не менялись
+func taskMoment(task Task, loc *time.Location) (time.Time, error) {
+	if !task.moment.IsZero() {
+		local := task.moment.In(loc)
+		if local.Format("20060102") == task.Date && local.Format("15:04") == task.Time {
+			return local, nil
+		}
+	}
+	date, err := time.Parse("20060102", task.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ошибка парсинга даты: %v", err)
+	}
+	var clock time.Time
+	if task.Time != "" {
+		if clock, err = time.Parse("15:04", task.Time); err != nil {
+			return time.Time{}, fmt.Errorf("ошибка парсинга времени: %v", err)
+		}
+	}
+	return localTime(date, clock, loc), nil
+}
+
+// rebaseOnCompletion переносит задачу в режиме completion на момент выполнения now,
+// чтобы следующее повторение отсчитывалось от дня (для правил h и n — от времени) выполнения,
+// а не от даты задачи. Задачи по расписанию не меняются
+func rebaseOnCompletion(task Task, now time.Time) (Task, error) {
+	if task.Mode != modeCompletion {
+		return task, nil
+	}
+	loc, err := taskLocation(task)
+	if err != nil {
+		return Task{}, err
+	}
+	local := now.In(loc)
+	task.Date, task.RuleDate = local.Format("20060102"), ""
+	if isIntradayRule(task.Repeat) {
+		task.Time = local.Format("15:04")
+		task.moment = local.Truncate(time.Minute)
+	}
+	return task, nil
+}
+
+// advanceTask переносит периодическую задачу на следующее повторение после now и уменьшает
+// число оставшихся повторений. Возвращает errNoMoreOccurrences, если повторения закончились
+// по правилу, числу повторений count или дате until
+func advanceTask(cal *calendar, task Task, now time.Time) (Task, error) {
+	if task.Count != "" {
+		count, err := strconv.Atoi(task.Count)
+		if err != nil {
+			return Task{}, fmt.Errorf("неправильное число повторений: %s", task.Count)
+		}
+		if count <= 1 {
+			return Task{}, errNoMoreOccurrences
+		}
+		task.Count = strconv.Itoa(count - 1)
+	}
+	return nextOccurrence(cal, task, now)
+}
+
+// checkNewTask проверяет новую задачу и вычисляет дату, с которой она будет сохранена
+func checkNewTask(cal *calendar, task Task) (Task, error) {
+	if err := checkDueTime(task); err != nil {
+		return Task{}, err
+	}
+	// Сегодняшний день определяется в часовом поясе задачи
+	now, err := taskToday(task)
+	if err != nil {
+		return Task{}, err
+	}
+
+	// Парсим дату задачи
+	var taskDate time.Time
+
+	// Если дата не указана, подставляем сегодняшнюю
+	if task.Date == "" {
+		taskDate = now
+	} else {
+		// Если дата указана, пытаемся её распарсить
+		taskDate, err = time.Parse("20060102", task.Date)
+		if err != nil {
+			return Task{}, fmt.Errorf("неправильный формат даты, ожидается YYYYMMDD: %v", err)
+		}
+	}
+
+	task, err = checkRepeatLimits(cal, task, taskDate)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if task.Repeat == "" {
+		// Если дата меньше сегодняшнего дня, подставляем текущую дату
+		if taskDate.Before(now) {
+			taskDate = now
+		}
+	} else {
+		// Проверяем правило повторения
+		if _, err := nextDate(cal, now, taskDate, task.Repeat); err != nil {
+			return Task{}, fmt.Errorf("не удалось вычислить следующую дату выполнения: %v", err)
+		}
+		// Если дата уже прошла или попадает на исключение, переносим задачу на ближайшую дату по правилу
+		if taskDate.Before(now) || isExdate(task, taskDate) {
+			task.Date = taskDate.Format("20060102")
+			task, err = nextOccurrence(cal, task, time.Now())
+			if err != nil {
+				return Task{}, fmt.Errorf("не удалось вычислить следующую дату выполнения: %v", err)
+			}
+			return task, nil
+		}
+	}
+
+	task.Date = taskDate.Format("20060102")
+	return task, nil
+}
+
+// parsedRepeat добавляет в ответ правило повторения, разобранное из записи словами,
+// и его описание, чтобы клиент мог показать пользователю, как понято правило
+func parsedRepeat(req *http.Request, result map[string]any, task Task, parsed bool) map[string]any {
+	if parsed {
+		result["repeat"] = task.Repeat
+		result["repeat_text"] = withRepeatText(req, task).RepeatText
+	}
+	return result
+}
+
+func (s *server) handleMain(res http.ResponseWriter, req *http.Request) {
+	// Без действительного токена вместо планировщика показываем страницу входа
+	if req.URL.Path == "/" || req.URL.Path == "/index.html" {
+		_, ok, err := s.authenticate(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Redirect(res, req, "/login.html", http.StatusFound)
+			return
+		}
+	}
+	http.StripPrefix("/", http.FileServer(http.Dir(s.config.WebDir))).ServeHTTP(res, req)
+}
+
+func handleTask(store TaskStore, cal *calendar) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := currentUser(req)
+
+		switch req.Method {
+		case http.MethodGet:
+			// Получаем ID из запроса
+			id := req.URL.Query().Get("id")
+			if id == "" {
+				writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+				return
+			}
+
+			task, err := store.Get(userID, id)
+			if err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			writeJSON(res, http.StatusOK, withDue(withRepeatText(req, task)))
+
+		case http.MethodPut:
+			var task Task
+			if err := json.NewDecoder(req.Body).Decode(&task); err != nil {
+				writeError(res, http.StatusBadRequest, "Неверный формат JSON")
+				return
+			}
+			if task.ID == "" {
+				writeError(res, http.StatusBadRequest, "Поле 'id' является обязательным")
+				return
+			}
+			if task.Title == "" {
+				writeError(res, http.StatusBadRequest, "Поле 'title' является обязательным")
+				return
+			}
+
+			repeat, parsed, err := normalizeRepeat(task.Repeat)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, err.Error())
+				return
+			}
+			task.Repeat = repeat
+			task, err = checkTaskUpdate(cal, task)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := store.Update(userID, task); err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			writeJSON(res, http.StatusOK, parsedRepeat(req, map[string]any{}, task, parsed))
+
+		case http.MethodPost:
+			var task Task
+			if err := json.NewDecoder(req.Body).Decode(&task); err != nil {
+				writeError(res, http.StatusBadRequest, "Неверный формат JSON")
+				return
+			}
+			if task.Title == "" {
+				writeError(res, http.StatusBadRequest, "Поле 'title' является обязательным")
+				return
+			}
+
+			repeat, parsed, err := normalizeRepeat(task.Repeat)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, err.Error())
+				return
+			}
+			task.Repeat = repeat
+			task, err = checkNewTask(cal, task)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, err.Error())
+				return
+			}
+			id, err := store.Create(userID, task)
+			if err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			writeJSON(res, http.StatusCreated, parsedRepeat(req, map[string]any{
+				"id": id,
+			}, task, parsed))
+
+		case http.MethodDelete:
+			id := req.URL.Query().Get("id")
+			if id == "" {
+				writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+				return
+			}
+
+			// Задача переносится в корзину, откуда её можно восстановить
+			if err := store.Delete(userID, id, time.Now()); err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			// Возвращаем пустой JSON в случае успешного удаления
+			writeJSON(res, http.StatusOK, map[string]any{})
+
+		default:
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		}
+	}
+}
+
+func handleGetTasks(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		// Строка вида 02.01.2006 ищет задачи на эту дату, любая другая — подстроку
+		// в заголовке или комментарии. status показывает выполненные (done), архивные (archived)
+		// или все (all) задачи вместо активных
+		filter := TaskFilter{Limit: 50, Status: req.URL.Query().Get("status")}
+		switch filter.Status {
+		case "", statusActive, statusDone, statusArchived, statusAll:
+		default:
+			writeError(res, http.StatusBadRequest, fmt.Sprintf("Неизвестное состояние задач: %s, ожидается active, done, archived или all", filter.Status))
+			return
+		}
+		if search := strings.TrimSpace(req.URL.Query().Get("search")); search != "" {
+			if date, err := time.Parse("02.01.2006", search); err == nil {
+				filter.Date = date.Format("20060102")
+			} else {
+				filter.Search = search
+			}
+		}
+
+		tasks, err := store.List(currentUser(req), filter)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		if tasks == nil {
+			tasks = []Task{} // Возвращаем пустой список вместо nil
+		}
+		for i := range tasks {
+			tasks[i] = withDue(withRepeatText(req, tasks[i]))
+		}
+
+		writeJSON(res, http.StatusOK, map[string]any{
+			"tasks": tasks,
+		})
+	}
+}
+
+func handleTaskDone(store TaskStore, cal *calendar) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		// Получаем идентификатор из запроса
+		id := req.URL.Query().Get("id")
+		if id == "" {
+			writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+			return
+		}
+
+		userID := currentUser(req)
+		task, err := store.Get(userID, id)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+
+		// Одноразовая задача (с пустым repeat) и задача, у которой закончились повторения,
+		// становятся выполненными (done), периодическая переносится на следующую дату: по расписанию
+		// или, в режиме completion, от дня выполнения
+		now := time.Now()
+		done := newCompletion(task, now)
+		if task.Repeat == "" {
+			task.Date = ""
+		} else {
+			advanced, err := rebaseOnCompletion(task, now)
+			if err == nil {
+				advanced, err = advanceTask(cal, advanced, now)
+			}
+			switch {
+			case err == nil:
+				task = advanced
+			case errors.Is(err, errNoMoreOccurrences):
+				task.Date = ""
+			default:
+				writeError(res, http.StatusInternalServerError, fmt.Sprintf("Ошибка вычисления следующей даты: %v", err))
+				return
+			}
+		}
+
+		if err := store.Complete(userID, task, done); err != nil {
+			writeStoreError(res, err)
+			return
+		}
+
+		// Возвращаем пустой JSON в случае успешного выполнения
+		writeJSON(res, http.StatusOK, map[string]any{})
+	}
+}
+
+// handleTaskArchive переносит активную задачу в архив, не отмечая её выполненной
+func handleTaskArchive(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		if id == "" {
+			writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+			return
+		}
+
+		if err := store.Archive(currentUser(req), id, time.Now()); err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		writeJSON(res, http.StatusOK, map[string]any{})
+	}
+}
+
+// handleTaskSkip пропускает текущее повторение периодической задачи, не отмечая его
+// выполненным: задача переносится на следующее повторение, а если их больше нет, — в архив.
+// Пропущенное повторение не считается выполненным, поэтому число повторений count не уменьшается
+func handleTaskSkip(store TaskStore, cal *calendar) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		if id == "" {
+			writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+			return
+		}
+
+		userID := currentUser(req)
+		task, err := store.Get(userID, id)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		if task.Repeat == "" {
+			writeError(res, http.StatusBadRequest, "Пропустить можно только повторение периодической задачи")
+			return
+		}
+
+		next, err := nextOccurrence(cal, task, time.Now())
+		switch {
+		case err == nil:
+			err = store.Update(userID, next)
+		case errors.Is(err, errNoMoreOccurrences):
+			err = store.Archive(userID, id, time.Now())
+		default:
+			writeError(res, http.StatusInternalServerError, fmt.Sprintf("Ошибка вычисления следующей даты: %v", err))
+			return
+		}
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+
+		writeJSON(res, http.StatusOK, map[string]any{
+			"date": next.Date,
+		})
+	}
+}
+
+// maxOccurrences ограничивает число дат в ответе /api/occurrences, а occurrencesScanLimit —
+// число перебираемых повторений, чтобы частое правило с далёким диапазоном не занимало сервер
+const (
+	maxOccurrences       = 500
+	occurrencesScanLimit = 20000
+)
+
+// firstOccurrence переносит задачу на первое повторение не раньше её даты: дата задачи
+// может не подходить правилам w, m и RRULE с BYDAY или BYMONTHDAY. Правила d, b и y
+// отсчитываются от самой даты, а время правил h и n уже задано задачей.
+// Повторение сдвигается с выходных по shift, а дата-исключение пропускается
+func firstOccurrence(cal *calendar, task Task) (Task, error) {
+	if task.Repeat == "" || task.RuleDate != "" || isIntradayRule(task.Repeat) {
+		return task, nil
+	}
+	date, err := time.Parse("20060102", task.Date)
+	if err != nil {
+		return Task{}, fmt.Errorf("ошибка парсинга даты: %v", err)
+	}
+
+	first := date
+	before := date.AddDate(0, 0, -1)
+	if isRRule(task.Repeat) {
+		r, err := parseRRule(task.Repeat)
+		if err != nil {
+			return Task{}, err
+		}
+		if first, _, err = r.after(date, before); err != nil {
+			return Task{}, err
+		}
+	} else if r, err := parseRule(task.Repeat); err != nil {
+		return Task{}, err
+	} else if r.kind == "w" || r.kind == "m" {
+		if first, err = r.step(cal, before); err != nil {
+			return Task{}, err
+		}
+	}
+
+	shifted, err := cal.shift(first, task.Shift)
+	if err != nil {
+		return Task{}, err
+	}
+	if task.Until != "" && shifted.Format("20060102") > task.Until {
+		return Task{}, errNoMoreOccurrences
+	}
+	task.Date = shifted.Format("20060102")
+	if !shifted.Equal(first) {
+		task.RuleDate = first.Format("20060102")
+	}
+	if isExdate(task, shifted) {
+		// Исключённое повторение не расходует число повторений count
+		loc, err := taskLocation(task)
+		if err != nil {
+			return Task{}, err
+		}
+		moment, err := taskMoment(task, loc)
+		if err != nil {
+			return Task{}, err
+		}
+		return nextOccurrence(cal, task, moment)
+	}
+	return task, nil
+}
+
+// occurrences возвращает даты повторений задачи начиная с первого повторения не раньше
+// её даты: не больше limit дат
+// в диапазоне from..to (нулевые границы не ограничивают), а для задачи со временем —
+// и время каждого повторения. truncated сообщает, что перебор остановился
+// на occurrencesScanLimit раньше, чем закончился диапазон
+func occurrences(cal *calendar, task Task, limit int, from, to time.Time) (dates, times []string, truncated bool, err error) {
+	dates = []string{}
+	loc, err := taskLocation(task)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	task, err = firstOccurrence(cal, task)
+	if errors.Is(err, errNoMoreOccurrences) {
+		return dates, times, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for i := 0; len(dates) < limit; i++ {
+		if i == occurrencesScanLimit {
+			return dates, times, true, nil
+		}
+		date, err := time.Parse("20060102", task.Date)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("ошибка парсинга даты: %v", err)
+		}
+		if !to.IsZero() && date.After(to) {
+			break
+		}
+		if !date.Before(from) {
+			dates = append(dates, task.Date)
+			if task.Time != "" {
+				times = append(times, task.Time)
+			}
+		}
+		if task.Repeat == "" {
+			break
+		}
+
+		moment, err := taskMoment(task, loc)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		task, err = advanceTask(cal, task, moment)
+		if errors.Is(err, errNoMoreOccurrences) {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return dates, times, false, nil
+}
+
+// handleOccurrences возвращает ближайшие повторения задачи id или правила repeat от даты date
+// (с переносом shift): limit дат (по умолчанию 10) или все даты в диапазоне from..to,
+// но не больше maxOccurrences
+func handleOccurrences(store TaskStore, cal *calendar) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+		query := req.URL.Query()
+
+		var task Task
+		if id := query.Get("id"); id != "" {
+			var err error
+			task, err = store.Get(currentUser(req), id)
+			if err != nil {
+				writeStoreError(res, err)
+				return
+			}
+		} else {
+			// Правило проверяется при вычислении повторений: для предпросмотра
+			// оно не обязано срабатывать после сегодняшнего дня
+			task = Task{Date: query.Get("date"), Repeat: query.Get("repeat"), Shift: query.Get("shift"),
+				Time: query.Get("time"), TZ: query.Get("tz")}
+			if task.Date == "" {
+				now, err := taskToday(task)
+				if err != nil {
+					writeError(res, http.StatusBadRequest, err.Error())
+					return
+				}
+				task.Date = now.Format("20060102")
+			}
+			taskDate, err := time.Parse("20060102", task.Date)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Неправильный формат параметра date, ожидается YYYYMMDD: %s", task.Date))
+				return
+			}
+			if err = checkDueTime(task); err == nil {
+				task, err = checkRepeatLimits(cal, task, taskDate)
+			}
+			if err != nil {
+				writeError(res, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		var from, to time.Time
+		for _, bound := range []struct {
+			name  string
+			value *time.Time
+		}{{"from", &from}, {"to", &to}} {
+			if param := query.Get(bound.name); param != "" {
+				var err error
+				*bound.value, err = time.Parse("20060102", param)
+				if err != nil {
+					writeError(res, http.StatusBadRequest, fmt.Sprintf("Неправильный формат параметра %s, ожидается YYYYMMDD: %s", bound.name, param))
+					return
+				}
+			}
+		}
+
+		// Без диапазона по умолчанию показываем 10 дат, с диапазоном — все, но не больше maxOccurrences
+		limit := 10
+		if !to.IsZero() {
+			limit = maxOccurrences
+		}
+		if param := query.Get("limit"); param != "" {
+			var err error
+			limit, err = strconv.Atoi(param)
+			if err != nil || limit < 1 || limit > maxOccurrences {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Параметр limit должен быть числом от 1 до %d", maxOccurrences))
+				return
+			}
+		}
+
+		dates, times, truncated, err := occurrences(cal, task, limit, from, to)
+		if err != nil {
+			writeError(res, http.StatusBadRequest, err.Error())
+			return
+		}
+		result := map[string]any{
+			"dates": dates,
+		}
+		if times != nil {
+			result["times"] = times
+		}
+		if truncated {
+			result["truncated"] = true
+		}
+		writeJSON(res, http.StatusOK, result)
+	}
+}
+
+// handleNextDate возвращает следующую дату по правилу repeat после date и now,
+// перенося её с выходных и праздников календаря cal по правилу shift
+func handleNextDate(cal *calendar) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		// Точка отсчёта: если now не передан, считаем от сегодняшнего дня в часовом поясе tz
+		now, err := taskToday(Task{TZ: req.URL.Query().Get("tz")})
+		if err != nil {
+			writeError(res, http.StatusBadRequest, err.Error())
+			return
+		}
+		if nowParam := req.URL.Query().Get("now"); nowParam != "" {
+			now, err = time.Parse("20060102", nowParam)
+			if err != nil {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Неправильный формат параметра now, ожидается YYYYMMDD: %s", nowParam))
+				return
+			}
+		}
+
+		dateParam := req.URL.Query().Get("date")
+		date, err := time.Parse("20060102", dateParam)
+		if err != nil {
+			writeError(res, http.StatusBadRequest, fmt.Sprintf("Неправильный формат параметра date, ожидается YYYYMMDD: %s", dateParam))
+			return
+		}
+
+		// shift переносит результат с выходных и праздников, как у задачи с тем же полем
+		_, next, err := nextShiftedDate(cal, now, date, req.URL.Query().Get("repeat"), req.URL.Query().Get("shift"), nil)
+		if err != nil {
+			writeError(res, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		res.WriteHeader(http.StatusOK)
+		res.Write([]byte(next.Format("20060102")))
+	}
+}
+
+// handleRepeatConvert переводит правило повторения между форматом d, y, w, m и RRULE.
+// В ответе есть оба представления, если правило выражается в обоих форматах
+func handleRepeatConvert(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+
+	repeat := strings.TrimSpace(req.URL.Query().Get("repeat"))
+	if repeat == "" {
+		writeError(res, http.StatusBadRequest, "Не указано правило повторения")
+		return
+	}
+
+	converted, err := toRRule(repeat)
+	if err != nil {
+		writeError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := map[string]string{
+		"rrule": converted,
+	}
+	if !isRRule(repeat) {
+		result["repeat"] = repeat
+	} else if native, err := fromRRule(repeat); err == nil {
+		result["repeat"] = native
+	}
+	writeJSON(res, http.StatusOK, result)
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(handler http.HandlerFunc, method, target string, body any) (int, map[string]any) {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, target, bytes.NewReader(data)))
+
+	var m map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &m)
+	return rec.Code, m
+}
+
+func TestHandlersWithMemoryStore(t *testing.T) {
+	s := &server{store: newMemoryStore(), calendar: &calendar{}}
+	task := s.auth(handleTask(s.store, s.calendar))
+	tasks := s.auth(handleGetTasks(s.store))
+	done := s.auth(handleTaskDone(s.store, s.calendar))
+	history := s.auth(handleHistory(s.store))
+
+	date := today().Format("20060102")
+
+	code, m := serve(task, http.MethodPost, "/api/task", map[string]any{"title": ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+
+	code, m = serve(task, http.MethodPost, "/api/task", map[string]any{
+		"date": date, "title": "Позвонить в УК", "comment": "горячая вода",
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	oneOff := "1"
+	assert.Equal(t, float64(1), m["id"])
+
+	code, _ = serve(task, http.MethodPost, "/api/task", map[string]any{
+		"date": date, "title": "Поплавать", "repeat": "d 7",
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	repeating := "2"
+
+	code, m = serve(task, http.MethodGet, "/api/task?id="+oneOff, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Позвонить в УК", m["title"])
+
+	code, m = serve(task, http.MethodGet, "/api/task?id=42", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.NotEmpty(t, m["error"])
+
+	_, m = serve(tasks, http.MethodGet, "/api/tasks?search=ук", nil)
+	assert.Len(t, m["tasks"], 1)
+	_, m = serve(tasks, http.MethodGet, "/api/tasks?search="+today().Format("02.01.2006"), nil)
+	assert.Len(t, m["tasks"], 2)
+
+	code, m = serve(task, http.MethodPut, "/api/task", map[string]any{
+		"id": repeating, "date": date, "title": "Поплавать", "repeat": "ooops",
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+
+	code, _ = serve(done, http.MethodPost, "/api/task/done?id="+repeating, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(task, http.MethodGet, "/api/task?id="+repeating, nil)
+	assert.Equal(t, today().AddDate(0, 0, 7).Format("20060102"), m["date"])
+
+	code, _ = serve(done, http.MethodPost, "/api/task/done?id="+oneOff, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(task, http.MethodGet, "/api/task?id="+oneOff, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	// Выполненная одноразовая задача остаётся в архиве
+	_, m = serve(tasks, http.MethodGet, "/api/tasks?status=done", nil)
+	if assert.Len(t, m["tasks"], 1) {
+		assert.Equal(t, "done", m["tasks"].([]any)[0].(map[string]any)["status"])
+	}
+	code, _ = serve(task, http.MethodDelete, "/api/task?id="+oneOff, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Журнал выполнения остаётся и у удалённой одноразовой задачи
+	_, m = serve(history, http.MethodGet, "/api/history?id="+oneOff, nil)
+	if assert.Len(t, m["completions"], 1) {
+		entry := m["completions"].([]any)[0].(map[string]any)
+		assert.Equal(t, "Позвонить в УК", entry["title"])
+		assert.Equal(t, date, entry["date"])
+	}
+	_, m = serve(history, http.MethodGet, "/api/history", nil)
+	assert.Len(t, m["completions"], 2)
+
+	code, _ = serve(task, http.MethodDelete, "/api/task?id="+repeating, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(tasks, http.MethodGet, "/api/tasks", nil)
+	assert.NotNil(t, m["tasks"])
+	assert.Empty(t, m["tasks"])
+}
+
+func TestFirstOccurrence(t *testing.T) {
+	tbl := []struct {
+		task Task
+		want []string
+	}{
+		{Task{Date: "20240101", Repeat: "w 5", Exdates: "20240105", Count: "2"}, []string{"20240112", "20240119"}},
+		{Task{Date: "20240106", Repeat: "d 7", Shift: "next", Count: "2"}, []string{"20240108", "20240115"}},
+		{Task{Date: "20240101", Repeat: "m 20", Until: "20240110"}, []string{}},
+	}
+	for _, v := range tbl {
+		dates, _, _, err := occurrences(&calendar{}, v.task, 10, time.Time{}, time.Time{})
+		assert.NoError(t, err, v.task)
+		assert.Equal(t, v.want, dates, v.task)
+	}
+}
+
+func TestOccurrencesFallBack(t *testing.T) {
+	// 25 октября 2026 года в Берлине часы переводятся с 03:00 на 02:00:
+	// время с 02:00 до 03:00 наступает дважды, и оба повторения должны попасть в список
+	tbl := []struct {
+		rule  string
+		clock string
+		dates []string
+		times []string
+	}{
+		{"h 1", "00:30", []string{"20261025", "20261025", "20261025", "20261025", "20261025"},
+			[]string{"00:30", "01:30", "02:30", "02:30", "03:30"}},
+		{"n 30", "01:30", []string{"20261025", "20261025", "20261025", "20261025", "20261025", "20261025"},
+			[]string{"01:30", "02:00", "02:30", "02:00", "02:30", "03:00"}},
+	}
+	for _, v := range tbl {
+		task := Task{Date: "20261025", Time: v.clock, Repeat: v.rule, TZ: "Europe/Berlin"}
+		dates, times, truncated, err := occurrences(&calendar{}, task, len(v.times), time.Time{}, time.Time{})
+		assert.NoError(t, err, v.rule)
+		assert.False(t, truncated, v.rule)
+		assert.Equal(t, v.dates, dates, v.rule)
+		assert.Equal(t, v.times, times, v.rule)
+	}
+
+	// Выполнение переносит задачу и через повторяющийся час
+	loc, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	task := Task{Date: "20261025", Time: "01:30", Repeat: "h 1", TZ: "Europe/Berlin"}
+	var dues []string
+	for i := 0; i < 3; i++ {
+		moment, err := taskMoment(task, loc)
+		assert.NoError(t, err)
+		task, err = advanceTask(&calendar{}, task, moment)
+		assert.NoError(t, err)
+		moment, err = taskMoment(task, loc)
+		assert.NoError(t, err)
+		dues = append(dues, moment.Format(time.RFC3339))
+	}
+	assert.Equal(t, []string{"2026-10-25T02:30:00+02:00", "2026-10-25T02:30:00+01:00", "2026-10-25T03:30:00+01:00"}, dues)
+}
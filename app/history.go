@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Completion — запись журнала выполнения: какая задача, на какую дату была запланирована
+// и когда её выполнили
+type Completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`
+	Date        string `json:"date"`           // плановая дата выполненного повторения
+	Time        string `json:"time,omitempty"` // плановое время, если оно было задано
+	CompletedAt string `json:"completed_at"`   // момент выполнения в UTC, RFC 3339
+}
+
+// newCompletion возвращает запись журнала о выполнении задачи в момент now
+func newCompletion(task Task, now time.Time) Completion {
+	return Completion{
+		TaskID:      task.ID,
+		Title:       task.Title,
+		Date:        task.Date,
+		Time:        task.Time,
+		CompletedAt: timestamp(now),
+	}
+}
+
+// maxHistory ограничивает число записей в ответе /api/history
+const maxHistory = 500
+
+// handleHistory возвращает журнал выполнения задачи id или всех задач пользователя, начиная
+// с последних: не больше limit записей (по умолчанию 50), выполненных с from по to включительно.
+// Журнал выполненной и удалённой одноразовой задачи тоже доступен
+func handleHistory(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+		query := req.URL.Query()
+
+		filter := CompletionFilter{TaskID: query.Get("id"), Limit: 50}
+		for _, bound := range []struct {
+			name  string
+			value *time.Time
+			days  int
+		}{{"from", &filter.From, 0}, {"to", &filter.To, 1}} {
+			if param := query.Get(bound.name); param != "" {
+				date, err := time.ParseInLocation("20060102", param, time.Local)
+				if err != nil {
+					writeError(res, http.StatusBadRequest, fmt.Sprintf("Неправильный формат параметра %s, ожидается YYYYMMDD: %s", bound.name, param))
+					return
+				}
+				// Граница to включает весь день
+				*bound.value = date.AddDate(0, 0, bound.days)
+			}
+		}
+		if param := query.Get("limit"); param != "" {
+			limit, err := strconv.Atoi(param)
+			if err != nil || limit < 1 || limit > maxHistory {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Параметр limit должен быть числом от 1 до %d", maxHistory))
+				return
+			}
+			filter.Limit = limit
+		}
+
+		history, err := store.History(currentUser(req), filter)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		if history == nil {
+			history = []Completion{}
+		}
+		writeJSON(res, http.StatusOK, map[string]any{
+			"completions": history,
+		})
+	}
+}
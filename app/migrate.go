@@ -0,0 +1,378 @@
+package app
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Миграции схемы лежат в migrations/ парами NNNN_name.up.sql и NNNN_name.down.sql
+// и встраиваются в бинарный файл. Применённые версии записываются в schema_migrations
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadMigrations читает встроенные миграции и возвращает их по возрастанию версии
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("неправильное имя файла миграции: %s", name)
+		}
+		number, title, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("неправильный номер миграции: %s", name)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("для миграции %04d нужны файлы up и down", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("пропущена миграция %04d", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// tableExists проверяет наличие таблицы в базе данных
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&exists)
+	return exists, err
+}
+
+// legacyVersion определяет версию схемы у баз, созданных до появления schema_migrations
+func legacyVersion(db *sql.DB) (int, error) {
+	exists, err := tableExists(db, "scheduler")
+	if err != nil || !exists {
+		return 0, err
+	}
+	var hasOwner bool
+	err = db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('scheduler') WHERE name = 'user_id'`).Scan(&hasOwner)
+	if err != nil {
+		return 0, err
+	}
+	if hasOwner {
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// appliedMigrations создаёт при необходимости таблицу schema_migrations
+// и возвращает время применения каждой версии
+func appliedMigrations(db *sql.DB, migrations []migration) (map[int]string, error) {
+	exists, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки таблицы миграций: %v", err)
+	}
+	if !exists {
+		legacy, err := legacyVersion(db)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка определения версии схемы: %v", err)
+		}
+		_, err = db.Exec(`
+			CREATE TABLE schema_migrations (
+				version INTEGER PRIMARY KEY NOT NULL,
+				name TEXT NOT NULL,
+				applied_at TEXT NOT NULL
+			);
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания таблицы миграций: %v", err)
+		}
+		// Схема уже существующей базы считается результатом первых legacy миграций
+		for _, m := range migrations[:min(legacy, len(migrations))] {
+			_, err = db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.Version, m.Name, time.Now().Format(time.DateTime))
+			if err != nil {
+				return nil, fmt.Errorf("ошибка записи версии схемы: %v", err)
+			}
+		}
+	}
+
+	return readAppliedMigrations(db)
+}
+
+// readAppliedMigrations читает из schema_migrations время применения каждой версии
+func readAppliedMigrations(db *sql.DB) (map[int]string, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы миграций: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения таблицы миграций: %v", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// migrationStatus возвращает время применения каждой версии, не изменяя базу: если таблицы
+// schema_migrations ещё нет, версии старой схемы отмечаются как применённые до учёта миграций
+func migrationStatus(db *sql.DB, migrations []migration) (map[int]string, error) {
+	exists, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки таблицы миграций: %v", err)
+	}
+	if exists {
+		return readAppliedMigrations(db)
+	}
+	legacy, err := legacyVersion(db)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка определения версии схемы: %v", err)
+	}
+	applied := make(map[int]string)
+	for _, m := range migrations[:min(legacy, len(migrations))] {
+		applied[m.Version] = "до учёта миграций"
+	}
+	return applied, nil
+}
+
+// runMigration выполняет скрипт миграции и отмечает результат в schema_migrations одной транзакцией
+func runMigration(db *sql.DB, m migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := m.Down
+	if up {
+		script = m.Up
+	}
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().Format(time.DateTime))
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backupDb сохраняет копию базы рядом с ней перед изменением схемы
+func backupDb(db *sql.DB, dbFilePath string) (string, error) {
+	stamp := time.Now().Format("20060102-150405")
+	backup := fmt.Sprintf("%s.%s.bak", dbFilePath, stamp)
+	// VACUUM INTO не перезаписывает файлы, поэтому копии в одну и ту же секунду нумеруем
+	for i := 1; fileExists(backup); i++ {
+		backup = fmt.Sprintf("%s.%s-%d.bak", dbFilePath, stamp, i)
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, backup); err != nil {
+		return "", fmt.Errorf("ошибка резервного копирования базы данных: %v", err)
+	}
+	return backup, nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// migrateUp применяет все неприменённые миграции. Если в базе уже есть данные,
+// перед этим делается резервная копия
+func migrateUp(db *sql.DB, dbFilePath string) ([]migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if len(applied) > 0 {
+		backup, err := backupDb(db, dbFilePath)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("Резервная копия базы данных:", backup)
+	}
+
+	for i, m := range pending {
+		if err := runMigration(db, m, true); err != nil {
+			return pending[:i], fmt.Errorf("ошибка применения миграции %04d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// migrateDown откатывает steps последних применённых миграций, предварительно сделав резервную копию
+func migrateDown(db *sql.DB, dbFilePath string, steps int) ([]migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var rollback []migration
+	for i := len(migrations) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if _, ok := applied[migrations[i].Version]; ok {
+			rollback = append(rollback, migrations[i])
+		}
+	}
+	if len(rollback) == 0 {
+		return nil, nil
+	}
+
+	backup, err := backupDb(db, dbFilePath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Резервная копия базы данных:", backup)
+
+	for i, m := range rollback {
+		if err := runMigration(db, m, false); err != nil {
+			return rollback[:i], fmt.Errorf("ошибка отката миграции %04d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	return rollback, nil
+}
+
+// RunMigrateCommand выполняет подкоманду migrate: status, up или down [N]
+func RunMigrateCommand(config Config, args []string) error {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	// status только читает базу и не создаёт файл, если его нет
+	dsn := config.DbFilePath
+	if command == "status" {
+		if !fileExists(config.DbFilePath) {
+			return fmt.Errorf("база данных %s не найдена, миграции не применялись", config.DbFilePath)
+		}
+		dsn = "file:" + config.DbFilePath + "?mode=ro"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+
+	switch command {
+	case "status":
+		migrations, err := loadMigrations()
+		if err != nil {
+			return err
+		}
+		applied, err := migrationStatus(db, migrations)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := "не применена"
+			if appliedAt, ok := applied[m.Version]; ok {
+				state = "применена " + appliedAt
+			}
+			fmt.Printf("%04d_%-20s %s\n", m.Version, m.Name, state)
+		}
+
+	case "up":
+		done, err := migrateUp(db, config.DbFilePath)
+		for _, m := range done {
+			fmt.Printf("Применена миграция %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("Схема базы данных актуальна")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("неправильное количество миграций для отката: %s", args[1])
+			}
+		}
+		done, err := migrateDown(db, config.DbFilePath, steps)
+		for _, m := range done {
+			fmt.Printf("Откачена миграция %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("Нет применённых миграций")
+		}
+
+	default:
+		return fmt.Errorf("неизвестная команда migrate %s, ожидается status, up или down [N]", command)
+	}
+
+	return nil
+}
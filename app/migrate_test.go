@@ -0,0 +1,38 @@
+package app
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateStatusReadOnly(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "scheduler.db")
+	db, err := sql.Open("sqlite", dbFile)
+	assert.NoError(t, err)
+	defer db.Close()
+	// База старой схемы, созданная до появления schema_migrations
+	_, err = db.Exec(`CREATE TABLE scheduler (id INTEGER PRIMARY KEY, date CHAR(8), title TEXT, comment TEXT, repeat VARCHAR(128))`)
+	assert.NoError(t, err)
+
+	migrations, err := loadMigrations()
+	assert.NoError(t, err)
+	applied, err := migrationStatus(db, migrations)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.Contains(t, applied, migrations[0].Version)
+
+	assert.NoError(t, RunMigrateCommand(Config{DbFilePath: dbFile}, []string{"status"}))
+	exists, err := tableExists(db, "schema_migrations")
+	assert.NoError(t, err)
+	assert.False(t, exists, "migrate status не должен изменять базу")
+
+	// Для несуществующей базы status не создаёт файл
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	assert.Error(t, RunMigrateCommand(Config{DbFilePath: missing}, []string{"status"}))
+	_, err = os.Stat(missing)
+	assert.True(t, os.IsNotExist(err))
+}
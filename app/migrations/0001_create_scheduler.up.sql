@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    date TEXT NOT NULL,
+    title TEXT NOT NULL,
+    comment TEXT,
+    repeat TEXT CHECK (LENGTH(repeat) <= 128)
+);
+
+CREATE INDEX IF NOT EXISTS scheduler_date ON scheduler(date);
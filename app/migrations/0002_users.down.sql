@@ -0,0 +1,5 @@
+DROP INDEX scheduler_user_date;
+
+ALTER TABLE scheduler DROP COLUMN user_id;
+
+DROP TABLE users;
@@ -0,0 +1,10 @@
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    login TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL
+);
+
+-- Существующие задачи остаются в общем списке (user_id = 0)
+ALTER TABLE scheduler ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX scheduler_user_date ON scheduler(user_id, date);
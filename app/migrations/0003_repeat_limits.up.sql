@@ -0,0 +1,3 @@
+-- Оставшееся число повторений (0 — без ограничения) и последняя дата повторения
+ALTER TABLE scheduler ADD COLUMN repeat_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN repeat_until TEXT NOT NULL DEFAULT '';
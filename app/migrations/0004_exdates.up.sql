@@ -0,0 +1,2 @@
+-- Даты-исключения периодической задачи в формате YYYYMMDD через запятую
+ALTER TABLE scheduler ADD COLUMN exdates TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Перенос повторения с выходных и праздников: '' — не переносить, 'next' или 'prev'
+ALTER TABLE scheduler ADD COLUMN shift TEXT NOT NULL DEFAULT '';
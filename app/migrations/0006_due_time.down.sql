@@ -0,0 +1,3 @@
+ALTER TABLE scheduler DROP COLUMN tz;
+
+ALTER TABLE scheduler DROP COLUMN due_time;
@@ -0,0 +1,3 @@
+-- Время выполнения HH:MM ('' — без времени) и часовой пояс IANA ('' — пояс сервера)
+ALTER TABLE scheduler ADD COLUMN due_time TEXT NOT NULL DEFAULT '';
+ALTER TABLE scheduler ADD COLUMN tz TEXT NOT NULL DEFAULT '';
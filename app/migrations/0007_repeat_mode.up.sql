@@ -0,0 +1,2 @@
+-- Отсчёт повторений: '' или 'schedule' — от даты задачи, 'completion' — от дня выполнения
+ALTER TABLE scheduler ADD COLUMN repeat_mode TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP INDEX completions_user_completed;
+
+DROP INDEX completions_user_task;
+
+DROP TABLE completions;
@@ -0,0 +1,14 @@
+-- Журнал выполнения задач. Записи не ссылаются на scheduler, потому что выполненная
+-- одноразовая задача удаляется, а её история остаётся
+CREATE TABLE completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    task_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    date TEXT NOT NULL,
+    due_time TEXT NOT NULL DEFAULT '',
+    completed_at TEXT NOT NULL
+);
+
+CREATE INDEX completions_user_task ON completions(user_id, task_id);
+CREATE INDEX completions_user_completed ON completions(user_id, completed_at);
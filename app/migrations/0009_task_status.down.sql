@@ -0,0 +1,5 @@
+DROP INDEX scheduler_status_archived;
+
+ALTER TABLE scheduler DROP COLUMN archived_at;
+
+ALTER TABLE scheduler DROP COLUMN status;
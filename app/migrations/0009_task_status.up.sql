@@ -0,0 +1,6 @@
+-- Состояние задачи: 'active', 'done' — выполнена, 'archived' — перенесена в архив.
+-- archived_at — момент перехода в done или archived в UTC, по нему архив очищается
+ALTER TABLE scheduler ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE scheduler ADD COLUMN archived_at TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX scheduler_status_archived ON scheduler(status, archived_at);
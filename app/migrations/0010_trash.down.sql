@@ -0,0 +1,5 @@
+DELETE FROM scheduler WHERE deleted_at != '';
+
+DROP INDEX scheduler_deleted;
+
+ALTER TABLE scheduler DROP COLUMN deleted_at;
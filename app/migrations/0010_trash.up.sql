@@ -0,0 +1,5 @@
+-- deleted_at — момент переноса задачи в корзину в UTC, пустая строка — задача не удалена.
+-- По нему корзина очищается
+ALTER TABLE scheduler ADD COLUMN deleted_at TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX scheduler_deleted ON scheduler(deleted_at);
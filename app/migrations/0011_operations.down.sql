@@ -0,0 +1,3 @@
+DROP INDEX operations_user;
+
+DROP TABLE operations;
@@ -0,0 +1,15 @@
+-- Журнал операций для отмены. kind — create, update, delete или done, task — задача
+-- до операции в JSON (для create пусто), completion_id — запись журнала выполнения,
+-- добавленная операцией done
+CREATE TABLE operations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    user_id INTEGER NOT NULL,
+    task_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    task TEXT NOT NULL DEFAULT '',
+    completion_id INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX operations_user ON operations(user_id, id);
@@ -0,0 +1,3 @@
+DROP INDEX task_changes_user_task;
+
+DROP TABLE task_changes;
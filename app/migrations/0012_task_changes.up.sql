@@ -0,0 +1,13 @@
+-- Журнал изменений задач: одна запись на каждое изменённое поле. Как и completions,
+-- записи не ссылаются на scheduler и остаются после удаления задачи
+CREATE TABLE task_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    task_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    changed_at TEXT NOT NULL
+);
+
+CREATE INDEX task_changes_user_task ON task_changes(user_id, task_id);
@@ -0,0 +1,3 @@
+-- Дата повторения по правилу до переноса с выходных. Пустая, если дата задачи не переносилась;
+-- от неё, а не от перенесённой даты отсчитываются следующие повторения
+ALTER TABLE scheduler ADD COLUMN rule_date TEXT NOT NULL DEFAULT '';
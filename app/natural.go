@@ -0,0 +1,434 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// isCanonicalRule проверяет, записано ли правило в формате d, b, y, w, m, h, n или RRULE,
+// а не словами
+func isCanonicalRule(rule string) bool {
+	if isRRule(rule) {
+		return true
+	}
+	parts := strings.Fields(rule)
+	if len(parts) == 0 {
+		return true
+	}
+	switch parts[0] {
+	case "d", "b", "y", "w", "m", "h", "n":
+		return true
+	}
+	return false
+}
+
+// normalizeRepeat переводит правило, записанное словами, в формат d, b, y, w, m или RRULE.
+// parsed сообщает, что правило было записано словами; правила в формате сервера не меняются
+func normalizeRepeat(rule string) (normalized string, parsed bool, err error) {
+	if isCanonicalRule(rule) {
+		return rule, false, nil
+	}
+	normalized, err = parseNaturalRule(rule)
+	if err != nil {
+		return "", false, err
+	}
+	return normalized, true, nil
+}
+
+// Единицы периода в правиле словами
+const (
+	unitNone = iota
+	unitDay
+	unitWeek
+	unitMonth
+	unitYear
+	unitHour
+	unitMinute
+)
+
+// naturalWord — значение слова правила: единица периода, день недели, месяц или порядковый номер
+type naturalWord struct {
+	unit     int
+	weekday  int // 1 — понедельник, 7 — воскресенье
+	month    int
+	ordinal  int
+	working  bool // рабочие дни
+	weekdays bool // будни, с понедельника по пятницу
+	other    bool // every other — через один
+	filler   bool // слово не влияет на правило
+}
+
+// naturalPrefixes сопоставляет началам слов их значения. Порядок важен: более длинные
+// и конфликтующие начала (month и mon, пятниц и пят, предпоследн и последн) проверяются раньше
+var naturalPrefixes = []struct {
+	prefix string
+	word   naturalWord
+}{
+	{"ежедневн", naturalWord{unit: unitDay}},
+	{"еженедельн", naturalWord{unit: unitWeek}},
+	{"ежемесячн", naturalWord{unit: unitMonth}},
+	{"ежегодн", naturalWord{unit: unitYear}},
+	{"daily", naturalWord{unit: unitDay}},
+	{"weekly", naturalWord{unit: unitWeek}},
+	{"monthly", naturalWord{unit: unitMonth}},
+	{"yearly", naturalWord{unit: unitYear}},
+	{"annual", naturalWord{unit: unitYear}},
+	{"ежечасн", naturalWord{unit: unitHour}},
+	{"hourly", naturalWord{unit: unitHour}},
+	{"минут", naturalWord{unit: unitMinute}},
+	{"minute", naturalWord{unit: unitMinute}},
+	{"weekday", naturalWord{weekdays: true}},
+	{"будн", naturalWord{weekdays: true}},
+	{"рабоч", naturalWord{working: true}},
+	{"working", naturalWord{working: true}},
+	{"business", naturalWord{working: true}},
+	{"month", naturalWord{unit: unitMonth}},
+	{"месяц", naturalWord{unit: unitMonth}},
+	{"недел", naturalWord{unit: unitWeek}},
+	{"week", naturalWord{unit: unitWeek}},
+	{"year", naturalWord{unit: unitYear}},
+
+	{"понедельн", naturalWord{weekday: 1}},
+	{"вторн", naturalWord{weekday: 2}},
+	{"сред", naturalWord{weekday: 3}},
+	{"четверг", naturalWord{weekday: 4}},
+	{"пятниц", naturalWord{weekday: 5}},
+	{"суббот", naturalWord{weekday: 6}},
+	{"воскресен", naturalWord{weekday: 7}},
+	{"mon", naturalWord{weekday: 1}},
+	{"tue", naturalWord{weekday: 2}},
+	{"wed", naturalWord{weekday: 3}},
+	{"thu", naturalWord{weekday: 4}},
+	{"fri", naturalWord{weekday: 5}},
+	{"sat", naturalWord{weekday: 6}},
+	{"sun", naturalWord{weekday: 7}},
+
+	{"январ", naturalWord{month: 1}},
+	{"феврал", naturalWord{month: 2}},
+	{"март", naturalWord{month: 3}},
+	{"апрел", naturalWord{month: 4}},
+	{"июн", naturalWord{month: 6}},
+	{"июл", naturalWord{month: 7}},
+	{"август", naturalWord{month: 8}},
+	{"сентябр", naturalWord{month: 9}},
+	{"октябр", naturalWord{month: 10}},
+	{"ноябр", naturalWord{month: 11}},
+	{"декабр", naturalWord{month: 12}},
+	{"jan", naturalWord{month: 1}},
+	{"feb", naturalWord{month: 2}},
+	{"mar", naturalWord{month: 3}},
+	{"apr", naturalWord{month: 4}},
+	{"jun", naturalWord{month: 6}},
+	{"jul", naturalWord{month: 7}},
+	{"aug", naturalWord{month: 8}},
+	{"sep", naturalWord{month: 9}},
+	{"oct", naturalWord{month: 10}},
+	{"nov", naturalWord{month: 11}},
+	{"dec", naturalWord{month: 12}},
+
+	{"перв", naturalWord{ordinal: 1}},
+	{"втор", naturalWord{ordinal: 2}},
+	{"трет", naturalWord{ordinal: 3}},
+	{"четвёрт", naturalWord{ordinal: 4}},
+	{"четверт", naturalWord{ordinal: 4}},
+	{"пят", naturalWord{ordinal: 5}},
+	{"предпоследн", naturalWord{ordinal: -2}},
+	{"последн", naturalWord{ordinal: -1}},
+	{"penultimate", naturalWord{ordinal: -2}},
+	{"first", naturalWord{ordinal: 1}},
+	{"second", naturalWord{ordinal: 2}},
+	{"third", naturalWord{ordinal: 3}},
+	{"fourth", naturalWord{ordinal: 4}},
+	{"fifth", naturalWord{ordinal: 5}},
+	{"last", naturalWord{ordinal: -1}},
+}
+
+// naturalWords — слова, которые сравниваются целиком
+var naturalWords = map[string]naturalWord{
+	"день": {unit: unitDay}, "дня": {unit: unitDay}, "дней": {unit: unitDay}, "днём": {unit: unitDay},
+	"day": {unit: unitDay}, "days": {unit: unitDay},
+	"год": {unit: unitYear}, "года": {unit: unitYear}, "лет": {unit: unitYear},
+	"час": {unit: unitHour}, "часа": {unit: unitHour}, "часов": {unit: unitHour},
+	"hour": {unit: unitHour}, "hours": {unit: unitHour}, "min": {unit: unitMinute}, "mins": {unit: unitMinute},
+	"май": {month: 5}, "мая": {month: 5}, "мае": {month: 5}, "may": {month: 5},
+	"other": {other: true},
+}
+
+// naturalFillers — служебные слова, которые не меняют правило
+var naturalFillers = map[string]bool{
+	"каждый": true, "каждая": true, "каждую": true, "каждое": true, "каждые": true, "каждого": true, "каждом": true,
+	"и": true, "в": true, "во": true, "по": true, "на": true, "раз": true, "число": true, "числа": true, "числам": true,
+	"каждой": true, "every": true, "each": true, "on": true, "the": true, "of": true, "and": true,
+	"in": true, "a": true, "at": true, "to": true,
+}
+
+// classifyWord определяет значение слова правила
+func classifyWord(word string) (naturalWord, bool) {
+	if naturalFillers[word] {
+		return naturalWord{filler: true}, true
+	}
+	if w, ok := naturalWords[word]; ok {
+		return w, true
+	}
+	for _, p := range naturalPrefixes {
+		if strings.HasPrefix(word, p.prefix) {
+			return p.word, true
+		}
+	}
+	return naturalWord{}, false
+}
+
+// naturalToken — слово или число правила
+type naturalToken struct {
+	text   string
+	number int  // для чисел
+	isNum  bool // слово — число, возможно с окончанием: 15, 1st, 2-го
+	suffix bool // у числа есть окончание порядкового числительного
+}
+
+// tokenizeNatural разбивает правило на слова в нижнем регистре, отделяя числа от окончаний
+func tokenizeNatural(rule string) []naturalToken {
+	rule = strings.ToLower(rule)
+	rule = strings.ReplaceAll(rule, "second to last", "penultimate")
+	fields := strings.FieldsFunc(rule, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+
+	var tokens []naturalToken
+	for _, field := range fields {
+		field = strings.Trim(field, "-")
+		if field == "" {
+			continue
+		}
+		digits := strings.IndexFunc(field, func(r rune) bool { return !unicode.IsDigit(r) })
+		if digits == 0 {
+			tokens = append(tokens, naturalToken{text: field})
+			continue
+		}
+		if digits < 0 {
+			digits = len(field)
+		}
+		number, _ := strconv.Atoi(field[:digits])
+		tokens = append(tokens, naturalToken{text: field, number: number, isNum: true, suffix: digits < len(field)})
+	}
+	return tokens
+}
+
+// isEveryWord сообщает, что слово — «каждый» в любой форме, every или each
+func isEveryWord(word string) bool {
+	return strings.HasPrefix(word, "кажд") || word == "every" || word == "each"
+}
+
+// isAndWord сообщает, что слово — союз «и» или and
+func isAndWord(word string) bool {
+	return word == "и" || word == "and"
+}
+
+// parseNaturalRule переводит правило, записанное словами по-русски или по-английски, в формат
+// сервера: «каждый понедельник и пятницу» — w 1,5, «every 2 weeks» — d 14,
+// «last day of month» — m -1, «10 и 17 января» — m 10,17 1
+func parseNaturalRule(rule string) (string, error) {
+	tokens := tokenizeNatural(rule)
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("правило не указано")
+	}
+
+	var (
+		unit        int   // период повторения
+		interval    = 1   // число периодов
+		monthDays   []int // дни месяца
+		weekdays    []string
+		months      []int
+		ordinals    []int // порядковые номера, которые ждут день недели или слово «день»
+		working     bool
+		hasInterval bool
+		everyOther  bool // every other — через один
+		perMonth    bool // в правиле есть слово «месяц»
+		everySecond = -1 // день недели после «каждый второй», «every second»
+		secondDay   bool // «каждый второй день», «every second day»
+	)
+	addMonth := func(month int) {
+		if !contains(months, month) {
+			months = append(months, month)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.isNum {
+			// Число перед единицей периода — интервал («каждые 3 дня»), иначе — день месяца
+			var next naturalWord
+			if i+1 < len(tokens) && !tokens[i+1].isNum {
+				next, _ = classifyWord(tokens[i+1].text)
+			}
+			if !tok.suffix && (next.unit != unitNone || next.working) {
+				if hasInterval || tok.number < 1 {
+					return "", fmt.Errorf("неправильный интервал в правиле: %s", tok.text)
+				}
+				interval, hasInterval = tok.number, true
+				continue
+			}
+			if tok.number < 1 || tok.number > 31 {
+				return "", fmt.Errorf("неправильный день месяца в правиле: %s", tok.text)
+			}
+			monthDays = append(monthDays, tok.number)
+			continue
+		}
+
+		w, ok := classifyWord(tok.text)
+		if !ok {
+			return "", fmt.Errorf("не удалось разобрать правило: неизвестное слово «%s»", tok.text)
+		}
+		switch {
+		case w.filler:
+		case w.ordinal != 0:
+			// Несколько порядковых числительных перечисляются через «и»: «первый и третий понедельник»
+			if len(ordinals) > 0 && !isAndWord(tokens[i-1].text) {
+				return "", fmt.Errorf("два порядковых числительных подряд: %s", tok.text)
+			}
+			ordinals = append(ordinals, w.ordinal)
+			if w.ordinal != 2 || i == 0 || !isEveryWord(tokens[i-1].text) || i+1 == len(tokens) {
+				continue
+			}
+			// «Каждый второй» перед днём недели или словом «день» решается после разбора
+			// всего правила, а перед неделей, месяцем или годом — это интервал, как every other
+			switch next, _ := classifyWord(tokens[i+1].text); {
+			case next.weekday != 0:
+				everySecond = len(weekdays)
+			case next.unit == unitDay:
+				secondDay = true
+			case next.unit != unitNone:
+				if hasInterval {
+					return "", fmt.Errorf("интервал указан дважды: %s", rule)
+				}
+				ordinals = nil
+				interval, hasInterval = 2, true
+			}
+		case w.weekday != 0:
+			item := strconv.Itoa(w.weekday)
+			if len(ordinals) == 0 {
+				weekdays = append(weekdays, item)
+			}
+			for _, ordinal := range ordinals {
+				weekdays = append(weekdays, item+"#"+strconv.Itoa(ordinal))
+			}
+			ordinals = nil
+		case w.unit == unitDay && len(ordinals) > 0:
+			// «последний день», «first day» — день месяца
+			monthDays = append(monthDays, ordinals...)
+			ordinals = nil
+		case w.month != 0:
+			addMonth(w.month)
+		case w.weekdays:
+			weekdays = append(weekdays, "1", "2", "3", "4", "5")
+		case w.working:
+			working = true
+		case w.other:
+			if hasInterval {
+				return "", fmt.Errorf("интервал указан дважды: %s", rule)
+			}
+			interval, hasInterval, everyOther = 2, true, true
+		case w.unit != unitNone:
+			if w.unit == unitMonth {
+				perMonth = true
+			}
+			// Месяц после дней месяца или дней недели («15 числа каждого месяца») не задаёт период
+			if w.unit == unitMonth && (len(monthDays) > 0 || len(weekdays) > 0) && !hasInterval {
+				continue
+			}
+			if unit != unitNone && unit != w.unit {
+				return "", fmt.Errorf("в правиле указаны разные периоды: %s", rule)
+			}
+			unit = w.unit
+		}
+	}
+	if len(ordinals) > 0 {
+		return "", fmt.Errorf("после порядкового числительного ожидается день недели или день месяца: %s", rule)
+	}
+	if len(weekdays) > 0 && len(monthDays) > 0 {
+		return "", fmt.Errorf("нельзя одновременно указать дни недели и дни месяца: %s", rule)
+	}
+	// «Каждый второй вторник» без слова «месяц» — вторник через неделю, как «every other tuesday»,
+	// а второй вторник месяца записывается как «второй вторник месяца»
+	if everySecond >= 0 && !perMonth && len(months) == 0 && !hasInterval && unit == unitNone {
+		weekdays[everySecond] = strings.TrimSuffix(weekdays[everySecond], "#2")
+		if !strings.Contains(strings.Join(weekdays, ","), "#") {
+			interval, hasInterval, everyOther = 2, true, true
+		} else {
+			weekdays[everySecond] += "#2"
+		}
+	}
+	// Так же «каждый второй день» — через день, а «каждый второй день месяца» — второе число
+	if secondDay && !perMonth && len(months) == 0 && !hasInterval && unit == unitNone && len(monthDays) == 1 {
+		monthDays = nil
+		unit, interval, hasInterval = unitDay, 2, true
+	}
+	if everyOther && unit == unitNone && len(weekdays) > 0 {
+		unit = unitWeek
+	}
+
+	var result string
+	monthList := ""
+	if len(months) > 0 {
+		monthList = " " + joinInts(months)
+	}
+	switch {
+	case unit == unitHour || unit == unitMinute:
+		// «каждые 2 часа по будням» — h 2 1,2,3,4,5
+		if len(monthDays) > 0 || len(months) > 0 || working || strings.Contains(strings.Join(weekdays, ","), "#") {
+			return "", fmt.Errorf("повторения внутри дня задаются только с днями недели: %s", rule)
+		}
+		result = fmt.Sprintf("n %d", interval)
+		if unit == unitHour {
+			result = fmt.Sprintf("h %d", interval)
+		}
+		if len(weekdays) > 0 {
+			result += " " + strings.Join(weekdays, ",")
+		}
+	case len(weekdays) > 0:
+		switch {
+		case hasInterval && unit == unitWeek && len(months) == 0 && !strings.Contains(strings.Join(weekdays, ","), "#"):
+			codes := make([]string, len(weekdays))
+			for i, day := range weekdays {
+				n, _ := strconv.Atoi(day)
+				codes[i] = weekdayCodes[n%7]
+			}
+			result = fmt.Sprintf("RRULE:FREQ=WEEKLY;INTERVAL=%d;BYDAY=%s", interval, strings.Join(codes, ","))
+		case hasInterval:
+			return "", fmt.Errorf("интервал с днями недели поддерживается только для недель: %s", rule)
+		default:
+			result = "w " + strings.Join(weekdays, ",") + monthList
+		}
+	case len(monthDays) > 0:
+		if hasInterval || (unit != unitNone && unit != unitMonth) {
+			return "", fmt.Errorf("интервал с днями месяца не поддерживается: %s", rule)
+		}
+		result = "m " + joinInts(monthDays) + monthList
+	case len(months) > 0:
+		return "", fmt.Errorf("укажите дни месяца или дни недели: %s", rule)
+	case working:
+		result = fmt.Sprintf("b %d", interval)
+	case unit == unitDay:
+		result = fmt.Sprintf("d %d", interval)
+	case unit == unitWeek:
+		result = fmt.Sprintf("d %d", 7*interval)
+	case unit == unitMonth && interval == 1:
+		result = "RRULE:FREQ=MONTHLY"
+	case unit == unitMonth:
+		result = fmt.Sprintf("RRULE:FREQ=MONTHLY;INTERVAL=%d", interval)
+	case unit == unitYear && interval == 1:
+		result = "y"
+	case unit == unitYear:
+		result = fmt.Sprintf("RRULE:FREQ=YEARLY;INTERVAL=%d", interval)
+	default:
+		return "", fmt.Errorf("не удалось разобрать правило: %s", rule)
+	}
+
+	// Праздники на то, срабатывает ли правило, не влияют, поэтому хватает календаря с одними выходными
+	if _, err := nextDate(&calendar{}, today(), today(), result); err != nil {
+		return "", fmt.Errorf("правило «%s» разобрано как %s: %v", rule, result, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNaturalRule(t *testing.T) {
+	tbl := []struct {
+		input string
+		want  string
+	}{
+		{"каждый понедельник и пятницу", "w 1,5"},
+		{"по понедельникам, средам и пятницам", "w 1,3,5"},
+		{"по будням", "w 1,2,3,4,5"},
+		{"every weekday", "w 1,2,3,4,5"},
+		{"every Monday and Friday", "w 1,5"},
+		{"каждый день", "d 1"},
+		{"ежедневно", "d 1"},
+		{"каждые 3 дня", "d 3"},
+		{"раз в 10 дней", "d 10"},
+		{"every 2 weeks", "d 14"},
+		{"every other week", "d 14"},
+		{"еженедельно", "d 7"},
+		{"каждые 2 недели по вторникам", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"every 2 weeks on Monday and Thursday", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"каждый рабочий день", "b 1"},
+		{"каждые 5 рабочих дней", "b 5"},
+		{"каждый час", "h 1"},
+		{"ежечасно", "h 1"},
+		{"каждые 2 часа по будням", "h 2 1,2,3,4,5"},
+		{"every 15 minutes", "n 15"},
+		{"every 30 mins on Saturday", "n 30 6"},
+		{"every 3 business days", "b 3"},
+		{"каждый месяц", "RRULE:FREQ=MONTHLY"},
+		{"every 3 months", "RRULE:FREQ=MONTHLY;INTERVAL=3"},
+		{"каждый год", "y"},
+		{"annually", "y"},
+		{"every 2 years", "RRULE:FREQ=YEARLY;INTERVAL=2"},
+		{"last day of month", "m -1"},
+		{"в последний день месяца", "m -1"},
+		{"предпоследний день месяца", "m -2"},
+		{"first day of the month", "m 1"},
+		{"15 числа каждого месяца", "m 15"},
+		{"on the 1st and 15th", "m 1,15"},
+		{"10 и 17 января, августа и декабря", "m 10,17 1,8,12"},
+		{"8 марта", "m 8 3"},
+		{"every May 9", "m 9 5"},
+		{"второй вторник месяца", "w 2#2"},
+		{"каждый второй вторник месяца", "w 2#2"},
+		{"каждый второй вторник", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"every second Tuesday", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"every other Monday", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO"},
+		{"every other week on Monday and Friday", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"каждую вторую пятницу и последнее воскресенье", "w 5#2,7#-1"},
+		{"каждый второй день", "d 2"},
+		{"every second day", "d 2"},
+		{"every other day", "d 2"},
+		{"каждый второй день месяца", "m 2"},
+		{"каждую вторую неделю", "d 14"},
+		{"every second month", "RRULE:FREQ=MONTHLY;INTERVAL=2"},
+		{"первый и третий понедельник", "w 1#1,1#3"},
+		{"first and third Monday of March", "w 1#1,1#3 3"},
+		{"первый и последний день месяца", "m 1,-1"},
+		{"в последнюю пятницу месяца", "w 5#-1"},
+		{"last Friday of January and July", "w 5#-1 1,7"},
+		{"second to last Sunday", "w 7#-2"},
+		{"first Monday and last Friday", "w 1#1,5#-1"},
+		{"по понедельникам в июне", "w 1 6"},
+	}
+	for _, v := range tbl {
+		got, err := parseNaturalRule(v.input)
+		assert.NoError(t, err, v.input)
+		assert.Equal(t, v.want, got, v.input)
+	}
+
+	for _, input := range []string{
+		"ooops",
+		"когда-нибудь",
+		"каждые 0 дней",
+		"каждые 500 дней",
+		"40 числа",
+		"в январе",
+		"первый",
+		"every 2 weeks on the 15th",
+		"каждые 2 месяца по понедельникам",
+		"31 февраля",
+		"каждые 2 часа 15 числа",
+		"каждые 25 часов",
+		"первый третий понедельник",
+		"каждую вторую неделю каждые 3 недели",
+	} {
+		_, err := parseNaturalRule(input)
+		assert.Error(t, err, input)
+	}
+
+	rule, parsed, err := normalizeRepeat("d 7")
+	assert.NoError(t, err)
+	assert.False(t, parsed)
+	assert.Equal(t, "d 7", rule)
+	rule, parsed, err = normalizeRepeat("every day")
+	assert.NoError(t, err)
+	assert.True(t, parsed)
+	assert.Equal(t, "d 1", rule)
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"fmt"
+	"time"
+)
+
+// today возвращает сегодняшнюю дату в часовом поясе сервера без времени,
+// в том же виде, что и time.Parse("20060102", ...)
+func today() time.Time {
+	return todayIn(time.Local)
+}
+
+// maxPastYears — насколько раньше now может быть дата, от которой вычисляется повторение,
+// а maxRuleSteps ограничивает число применений правила: /api/nextdate доступен без входа,
+// и один запрос не должен перебирать сотни тысяч дат
+const (
+	maxPastYears = 500
+	maxRuleSteps = 100000
+)
+
+// nextDate вычисляет ближайшую дату выполнения задачи, которая больше now.
+// Правило применяется к date хотя бы один раз, а затем до тех пор, пока результат не окажется позже now
+// Правила RRULE: вычисляются отдельно, см. nextRRuleDate. Рабочие дни правила b берутся из календаря cal
+func nextDate(cal *calendar, now, date time.Time, rule string) (time.Time, error) {
+	if date.Before(now.AddDate(-maxPastYears, 0, 0)) {
+		return time.Time{}, fmt.Errorf("дата %s раньше сегодняшней больше чем на %d лет", date.Format("20060102"), maxPastYears)
+	}
+	if isRRule(rule) {
+		return nextRRuleDate(now, date, rule)
+	}
+
+	r, err := parseRule(rule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Правило d сразу переносим на последнее повторение не позже now, не перебирая пропущенные
+	if r.kind == "d" && date.Before(now) {
+		date = date.AddDate(0, 0, daysBetween(date, now)/r.days*r.days)
+	}
+	next, err := r.step(cal, date)
+	for i := 0; err == nil && !next.After(now); i++ {
+		if i == maxRuleSteps {
+			return time.Time{}, fmt.Errorf("слишком много повторений с %s, укажите более позднюю дату", date.Format("20060102"))
+		}
+		next, err = r.step(cal, next)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return next, nil
+}
+
+// nextShiftedDate вычисляет ближайшую дату по правилу, которая после переноса с выходного
+// по правилу shift оказывается позже now и date и не отвергается skip. Выходные и праздники
+// берутся из календаря cal. Возвращает дату по правилу и дату после переноса
+func nextShiftedDate(cal *calendar, now, date time.Time, rule, shift string, skip func(time.Time) bool) (time.Time, time.Time, error) {
+	after := now
+	if date.After(after) {
+		after = date
+	}
+
+	next, err := nextDate(cal, now, date, rule)
+	for err == nil {
+		var shifted time.Time
+		shifted, err = cal.shift(next, shift)
+		if err == nil && shifted.After(after) && (skip == nil || !skip(shifted)) {
+			return next, shifted, nil
+		}
+		if err == nil {
+			next, err = nextDate(cal, next, date, rule)
+		}
+	}
+	return time.Time{}, time.Time{}, err
+}
+
+// resolveDay возвращает дату для дня месяца day (отрицательные дни отсчитываются от конца месяца)
+// и false, если такого дня в месяце нет
+func resolveDay(year, month, day int) (time.Time, bool) {
+	lastDay := time.Date(year, time.Month(month+1), 0, 0, 0, 0, 0, time.UTC).Day()
+	if day < 0 {
+		day = lastDay + day + 1
+	}
+	if day < 1 || day > lastDay {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// contains проверяет, содержится ли элемент в списке
+func contains(list []int, elem int) bool {
+	for _, v := range list {
+		if v == elem {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"time"
+)
+
+// retentionInterval — как часто очищаются архив и корзина
+const retentionInterval = time.Hour
+
+// purgeArchived удаляет выполненные и архивные задачи старше retention на момент now
+func purgeArchived(store TaskStore, retention time.Duration, now time.Time) {
+	purged, err := store.PurgeArchived(now.Add(-retention))
+	if err != nil {
+		fmt.Println("Ошибка очистки архива:", err)
+		return
+	}
+	if purged > 0 {
+		fmt.Printf("Из архива удалено задач: %d\n", purged)
+	}
+}
+
+// purgeTrash насовсем удаляет задачи, пролежавшие в корзине дольше retention на момент now
+func purgeTrash(store TaskStore, retention time.Duration, now time.Time) {
+	purged, err := store.PurgeDeleted(now.Add(-retention))
+	if err != nil {
+		fmt.Println("Ошибка очистки корзины:", err)
+		return
+	}
+	if purged > 0 {
+		fmt.Printf("Из корзины удалено задач: %d\n", purged)
+	}
+}
+
+// startRetention сразу и затем каждые retentionInterval удаляет выполненные и архивные задачи,
+// перешедшие в это состояние больше archive назад, и задачи, удалённые в корзину больше trash
+// назад. Нулевой срок хранит задачи бессрочно. Возвращает функцию, которая останавливает очистку
+func startRetention(store TaskStore, archive, trash time.Duration) (stop func()) {
+	if archive <= 0 && trash <= 0 {
+		return func() {}
+	}
+	purge := func(now time.Time) {
+		if archive > 0 {
+			purgeArchived(store, archive, now)
+		}
+		if trash > 0 {
+			purgeTrash(store, trash, now)
+		}
+	}
+	purge(time.Now())
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(retentionInterval)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				purge(now)
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeArchived(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		now := time.Now()
+		create := func(title string) string {
+			id, err := store.Create(1, Task{Date: now.Format("20060102"), Title: title})
+			assert.NoError(t, err, name)
+			return strconv.FormatInt(id, 10)
+		}
+		active := create("Активная")
+		old := create("Давно в архиве")
+		recent := create("Недавно выполнена")
+
+		assert.NoError(t, store.Archive(1, old, now.AddDate(0, 0, -40)), name)
+		assert.NoError(t, store.Complete(1, Task{ID: recent}, newCompletion(Task{ID: recent}, now)), name)
+		assert.ErrorIs(t, store.Archive(1, recent, now), ErrTaskNotFound, name)
+
+		purgeArchived(store, 30*24*time.Hour, now)
+
+		tasks, err := store.List(1, TaskFilter{Status: statusAll})
+		assert.NoError(t, err, name)
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		assert.Equal(t, []string{active, recent}, ids, name)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		now := time.Now()
+		create := func(title string) string {
+			id, err := store.Create(1, Task{Date: now.Format("20060102"), Title: title, Repeat: "d 3"})
+			assert.NoError(t, err, name)
+			return strconv.FormatInt(id, 10)
+		}
+		old := create("Удалена давно")
+		recent := create("Удалена недавно")
+		archived := create("Удалена из архива")
+
+		assert.NoError(t, store.Delete(1, old, now.AddDate(0, 0, -40)), name)
+		assert.NoError(t, store.Delete(1, recent, now), name)
+		assert.ErrorIs(t, store.Delete(1, recent, now), ErrTaskNotFound, name)
+		assert.NoError(t, store.Archive(1, archived, now), name)
+		assert.NoError(t, store.Delete(1, archived, now.Add(-time.Minute)), name)
+
+		// Задачи из корзины не видны остальным методам
+		_, err := store.Get(1, recent)
+		assert.ErrorIs(t, err, ErrTaskNotFound, name)
+		assert.ErrorIs(t, store.Update(1, Task{ID: recent, Title: "Новое"}), ErrTaskNotFound, name)
+		tasks, err := store.List(1, TaskFilter{Status: statusAll})
+		assert.NoError(t, err, name)
+		assert.Empty(t, tasks, name)
+
+		tasks, err = store.Trash(1)
+		assert.NoError(t, err, name)
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		assert.Equal(t, []string{recent, archived, old}, ids, name)
+		tasks, err = store.Trash(2)
+		assert.NoError(t, err, name)
+		assert.Empty(t, tasks, name)
+
+		purgeTrash(store, 30*24*time.Hour, now)
+		assert.ErrorIs(t, store.Restore(1, old), ErrTaskNotFound, name)
+
+		// Восстановленная задача возвращается в прежнем состоянии
+		assert.NoError(t, store.Restore(1, recent), name)
+		assert.NoError(t, store.Restore(1, archived), name)
+		assert.ErrorIs(t, store.Restore(1, recent), ErrTaskNotFound, name)
+		task, err := store.Get(1, recent)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "d 3", task.Repeat, name)
+		tasks, err = store.List(1, TaskFilter{Status: statusArchived})
+		assert.NoError(t, err, name)
+		if assert.Len(t, tasks, 1, name) {
+			assert.Equal(t, archived, tasks[0].ID, name)
+		}
+
+		assert.ErrorIs(t, store.Purge(1, recent), ErrTaskNotFound, name)
+		assert.NoError(t, store.Delete(1, recent, now), name)
+		assert.ErrorIs(t, store.Purge(2, recent), ErrTaskNotFound, name)
+		assert.NoError(t, store.Purge(1, recent), name)
+		tasks, err = store.Trash(1)
+		assert.NoError(t, err, name)
+		assert.Empty(t, tasks, name)
+	}
+}
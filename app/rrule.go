@@ -0,0 +1,420 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rrulePrefix отличает правила RFC 5545 от собственного синтаксиса d, y, w, m
+const rrulePrefix = "RRULE:"
+
+// rruleHorizon — сколько лет после точки отсчёта ищется ближайшее повторение RRULE,
+// прежде чем правило считается никогда не срабатывающим
+const rruleHorizon = 1000
+
+// errNoMoreOccurrences возвращается, когда у серии с COUNT или UNTIL не осталось повторений
+var errNoMoreOccurrences = errors.New("повторений больше нет")
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// rruleWeekday — элемент BYDAY: день недели с необязательным порядковым номером (2TU, -1FR)
+type rruleWeekday struct {
+	ord int // 0 — каждый такой день недели
+	day time.Weekday
+}
+
+// rrule — разобранное правило повторения RFC 5545. Поддерживаются FREQ=DAILY, WEEKLY,
+// MONTHLY и YEARLY с INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, COUNT, UNTIL и WKST=MO.
+// Началом серии (DTSTART) считается дата задачи
+type rrule struct {
+	freq       string
+	interval   int
+	byDay      []rruleWeekday
+	byMonthDay []int
+	byMonth    []int
+	count      int
+	until      time.Time
+}
+
+var byDayPattern = regexp.MustCompile(`^([+-]?\d{1,2})?(SU|MO|TU|WE|TH|FR|SA)$`)
+
+// isRRule сообщает, записано ли правило в формате RFC 5545
+func isRRule(rule string) bool {
+	return len(rule) >= len(rrulePrefix) && strings.EqualFold(rule[:len(rrulePrefix)], rrulePrefix)
+}
+
+// parseRRule разбирает строку вида RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR
+func parseRRule(rule string) (rrule, error) {
+	if !isRRule(rule) {
+		return rrule{}, fmt.Errorf("правило должно начинаться с %s: %s", rrulePrefix, rule)
+	}
+
+	r := rrule{interval: 1}
+	for _, part := range strings.Split(strings.TrimSpace(rule[len(rrulePrefix):]), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return rrule{}, fmt.Errorf("неправильная часть правила: %s", part)
+		}
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(value))
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return rrule{}, fmt.Errorf("частота %s не поддерживается", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 {
+				return rrule{}, fmt.Errorf("неправильный INTERVAL: %s", value)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err != nil || r.count < 1 {
+				return rrule{}, fmt.Errorf("неправильный COUNT: %s", value)
+			}
+		case "UNTIL":
+			// Время в UNTIL не учитывается: задачи планируются с точностью до дня
+			if len(value) < 8 {
+				return rrule{}, fmt.Errorf("неправильный UNTIL: %s", value)
+			}
+			r.until, err = time.Parse("20060102", value[:8])
+			if err != nil {
+				return rrule{}, fmt.Errorf("неправильный UNTIL: %s", value)
+			}
+		case "BYMONTH":
+			r.byMonth, err = parseIntList(value, 1, 12)
+			if err != nil {
+				return rrule{}, fmt.Errorf("неправильный BYMONTH: %v", err)
+			}
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseIntList(value, -31, 31)
+			if err != nil || contains(r.byMonthDay, 0) {
+				return rrule{}, fmt.Errorf("неправильный BYMONTHDAY: %s", value)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				m := byDayPattern.FindStringSubmatch(item)
+				if m == nil {
+					return rrule{}, fmt.Errorf("неправильный BYDAY: %s", item)
+				}
+				wd := rruleWeekday{}
+				if m[1] != "" {
+					wd.ord, _ = strconv.Atoi(m[1])
+					if wd.ord == 0 || wd.ord < -53 || wd.ord > 53 {
+						return rrule{}, fmt.Errorf("неправильный BYDAY: %s", item)
+					}
+				}
+				for i, code := range weekdayCodes {
+					if code == m[2] {
+						wd.day = time.Weekday(i)
+					}
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "WKST":
+			if value != "MO" {
+				return rrule{}, fmt.Errorf("поддерживается только WKST=MO")
+			}
+		default:
+			return rrule{}, fmt.Errorf("часть правила %s не поддерживается", key)
+		}
+	}
+
+	if r.freq == "" {
+		return rrule{}, fmt.Errorf("в правиле не указана частота FREQ: %s", rule)
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return rrule{}, fmt.Errorf("COUNT и UNTIL нельзя указывать одновременно")
+	}
+	for _, wd := range r.byDay {
+		if wd.ord != 0 && r.freq != "MONTHLY" && r.freq != "YEARLY" {
+			return rrule{}, fmt.Errorf("номер дня недели в BYDAY допустим только для FREQ=MONTHLY и FREQ=YEARLY")
+		}
+		if wd.ord != 0 && r.freq == "MONTHLY" && (wd.ord < -5 || wd.ord > 5) {
+			return rrule{}, fmt.Errorf("неправильный номер дня недели в месяце: %d", wd.ord)
+		}
+	}
+	return r, nil
+}
+
+// parseIntList строго разбирает список чисел через запятую в диапазоне [min, max]
+func parseIntList(input string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(input, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || value < min || value > max {
+			return nil, fmt.Errorf("неправильное значение %q", item)
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// daysBetween возвращает количество дней от a до b
+func daysBetween(a, b time.Time) int {
+	// Разность через Unix-время: time.Duration не вмещает промежутки длиннее 292 лет
+	return int((b.Unix() - a.Unix()) / (24 * 60 * 60))
+}
+
+// weekStart возвращает понедельник недели, в которую попадает date
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+// matchesWeekday проверяет день недели date с учётом порядкового номера:
+// в пределах месяца для MONTHLY и YEARLY с BYMONTH, иначе в пределах года
+func (r rrule) matchesWeekday(date time.Time, wd rruleWeekday) bool {
+	if date.Weekday() != wd.day {
+		return false
+	}
+	if wd.ord == 0 {
+		return true
+	}
+
+	day, last := date.Day(), time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if r.freq == "YEARLY" && len(r.byMonth) == 0 {
+		day, last = date.YearDay(), time.Date(date.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if wd.ord > 0 {
+		return (day-1)/7+1 == wd.ord
+	}
+	return (last-day)/7+1 == -wd.ord
+}
+
+// matches проверяет, является ли date повторением серии, начавшейся в start
+func (r rrule) matches(start, date time.Time) bool {
+	// Номер дня, недели, месяца или года от начала серии должен делиться на INTERVAL
+	var period int
+	switch r.freq {
+	case "DAILY":
+		period = daysBetween(start, date)
+	case "WEEKLY":
+		period = daysBetween(weekStart(start), weekStart(date)) / 7
+	case "MONTHLY":
+		period = (date.Year()-start.Year())*12 + int(date.Month()) - int(start.Month())
+	case "YEARLY":
+		period = date.Year() - start.Year()
+	}
+	if period%r.interval != 0 {
+		return false
+	}
+
+	if len(r.byMonth) > 0 && !contains(r.byMonth, int(date.Month())) {
+		return false
+	}
+
+	if len(r.byMonthDay) > 0 {
+		last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if !contains(r.byMonthDay, date.Day()) && !contains(r.byMonthDay, date.Day()-last-1) {
+			return false
+		}
+	}
+
+	if len(r.byDay) > 0 {
+		found := false
+		for _, wd := range r.byDay {
+			if r.matchesWeekday(date, wd) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// Части правила, которые не указаны, берутся из даты начала серии
+	switch r.freq {
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return date.Weekday() == start.Weekday()
+		}
+	case "MONTHLY":
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			return date.Day() == start.Day()
+		}
+	case "YEARLY":
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			if len(r.byMonth) == 0 && date.Month() != start.Month() {
+				return false
+			}
+			return date.Day() == start.Day()
+		}
+	}
+	return true
+}
+
+// after возвращает первое повторение серии, начавшейся в start, которое позже after,
+// и количество повторений серии до него, начиная со start
+func (r rrule) after(start, after time.Time) (time.Time, int, error) {
+	date, seen := start, 0
+	// Без COUNT повторения до after считать не нужно
+	if r.count == 0 && after.After(start) {
+		date = after
+	}
+	limit := after.AddDate(rruleHorizon, 0, 0)
+
+	for ; !date.After(limit); date = date.AddDate(0, 0, 1) {
+		if !r.until.IsZero() && date.After(r.until) {
+			return time.Time{}, seen, errNoMoreOccurrences
+		}
+		if !r.matches(start, date) {
+			continue
+		}
+		if date.After(after) {
+			return date, seen, nil
+		}
+		seen++
+		if r.count > 0 && seen >= r.count {
+			return time.Time{}, seen, errNoMoreOccurrences
+		}
+	}
+	return time.Time{}, seen, fmt.Errorf("правило не срабатывает в ближайшие %d лет", rruleHorizon)
+}
+
+// nextRRuleDate вычисляет ближайшее повторение RRULE после date и now
+func nextRRuleDate(now, date time.Time, rule string) (time.Time, error) {
+	r, err := parseRRule(rule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	after := date
+	if now.After(after) {
+		after = now
+	}
+	next, _, err := r.after(date, after)
+	return next, err
+}
+
+var countPattern = regexp.MustCompile(`(?i)(COUNT=)(\d+)`)
+
+// advanceRepeat возвращает правило для задачи, перенесённой с date на next.
+// Серия RRULE начинается с даты задачи, поэтому COUNT уменьшается на число
+// повторений, оставшихся позади; остальные правила не меняются
+func advanceRepeat(rule string, date, next time.Time) (string, error) {
+	if !isRRule(rule) {
+		return rule, nil
+	}
+	r, err := parseRRule(rule)
+	if err != nil || r.count == 0 {
+		return rule, err
+	}
+	_, passed, err := r.after(date, next.AddDate(0, 0, -1))
+	if err != nil {
+		return "", err
+	}
+	return countPattern.ReplaceAllString(rule, "${1}"+strconv.Itoa(r.count-passed)), nil
+}
+
+// String записывает правило в формате RFC 5545
+func (r rrule) String() string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval))
+	}
+	if len(r.byMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.byMonth))
+	}
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.byMonthDay))
+	}
+	if len(r.byDay) > 0 {
+		days := make([]string, len(r.byDay))
+		for i, wd := range r.byDay {
+			days[i] = weekdayCodes[wd.day]
+			if wd.ord != 0 {
+				days[i] = strconv.Itoa(wd.ord) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.Format("20060102"))
+	}
+	return rrulePrefix + strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = strconv.Itoa(v)
+	}
+	return strings.Join(items, ",")
+}
+
+// toRRule переводит правило d, y, w или m в формат RFC 5545
+func toRRule(rule string) (string, error) {
+	if isRRule(rule) {
+		r, err := parseRRule(rule)
+		if err != nil {
+			return "", err
+		}
+		return r.String(), nil
+	}
+	parsed, err := parseRule(rule)
+	if err != nil {
+		return "", err
+	}
+	r, err := parsed.toRRule()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, rule)
+	}
+	return r.String(), nil
+}
+
+// fromRRule переводит RRULE в правило d, y, w или m, если оно выражается ими без потерь
+func fromRRule(rule string) (string, error) {
+	r, err := parseRRule(rule)
+	if err != nil {
+		return "", err
+	}
+	impossible := fmt.Errorf("правило нельзя записать без RRULE: %s", rule)
+	if r.count > 0 || !r.until.IsZero() {
+		return "", impossible
+	}
+
+	switch {
+	case r.freq == "DAILY" && r.interval <= 400 && len(r.byDay)+len(r.byMonthDay)+len(r.byMonth) == 0:
+		return fmt.Sprintf("d %d", r.interval), nil
+
+	case r.freq == "YEARLY" && r.interval == 1 && len(r.byDay)+len(r.byMonthDay)+len(r.byMonth) == 0:
+		return "y", nil
+
+	case (r.freq == "WEEKLY" || r.freq == "MONTHLY") && r.interval == 1 && len(r.byDay) > 0 && len(r.byMonthDay) == 0:
+		days := make([]string, len(r.byDay))
+		for i, wd := range r.byDay {
+			days[i] = strconv.Itoa((int(wd.day)+6)%7 + 1)
+			if wd.ord != 0 {
+				days[i] += "#" + strconv.Itoa(wd.ord)
+			}
+		}
+		result := "w " + strings.Join(days, ",")
+		if len(r.byMonth) > 0 {
+			result += " " + joinInts(r.byMonth)
+		}
+		return result, nil
+
+	case r.freq == "MONTHLY" && r.interval == 1 && len(r.byMonthDay) > 0 && len(r.byDay) == 0:
+		result := "m " + joinInts(r.byMonthDay)
+		if len(r.byMonth) > 0 {
+			result += " " + joinInts(r.byMonth)
+		}
+		// Дни с конца месяца дальше предпоследнего в правиле m не записываются
+		if _, err := parseRule(result); err != nil {
+			return "", impossible
+		}
+		return result, nil
+	}
+	return "", impossible
+}
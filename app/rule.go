@@ -0,0 +1,404 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxRuleDays — наибольший интервал правил d и b
+const maxRuleDays = 400
+
+// monthLength — наибольшее число дней в каждом месяце с учётом високосного февраля
+var monthLength = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// ruleError — ошибка в правиле повторения с позицией неправильной части (с 1, в символах)
+type ruleError struct {
+	msg   string
+	token string
+	pos   int
+}
+
+func (e *ruleError) Error() string {
+	if e.token == "" {
+		return fmt.Sprintf("%s (позиция %d)", e.msg, e.pos)
+	}
+	return fmt.Sprintf("%s: «%s» (позиция %d)", e.msg, e.token, e.pos)
+}
+
+// ruleToken — часть правила и позиция её первого символа
+type ruleToken struct {
+	text string
+	pos  int
+}
+
+// repeatRule — разобранное правило d, b, y, w, m, h или n
+type repeatRule struct {
+	kind      string
+	days      int            // d, b: интервал в днях
+	weekdays  []rruleWeekday // w: дни недели, в том числе n-й день недели месяца; h, n: дни недели
+	monthDays []int          // m: дни месяца, -1 и -2 — последний и предпоследний
+	months    []int          // w, m: необязательные месяцы
+
+	interval   time.Duration // h, n: интервал в часах или минутах
+	hasWindow  bool          // h, n: повторения только с windowFrom по windowTo
+	windowFrom time.Time
+	windowTo   time.Time
+}
+
+// parseRule разбирает правило d, b, y, w, m, h или n. Любая неправильная часть правила —
+// ошибка с её позицией; правило m, которое не срабатывает ни в одном году (30 февраля), тоже ошибка
+func parseRule(rule string) (repeatRule, error) {
+	fields := splitRule([]rune(rule), 1, unicode.IsSpace, false)
+	if len(fields) == 0 {
+		return repeatRule{}, fmt.Errorf("правило не указано")
+	}
+	end := len([]rune(rule)) + 1
+
+	r := repeatRule{kind: fields[0].text}
+	// required и allowed — сколько частей после типа правила обязательно и сколько допустимо
+	var required, allowed int
+	switch r.kind {
+	case "d", "b":
+		required, allowed = 1, 1
+	case "y":
+		required, allowed = 0, 0
+	case "w", "m":
+		required, allowed = 1, 2
+	case "h", "n":
+		required, allowed = 1, 3
+	default:
+		return repeatRule{}, &ruleError{msg: "неизвестное правило", token: fields[0].text, pos: fields[0].pos}
+	}
+
+	args := fields[1:]
+	if len(args) < required {
+		missing := map[string]string{
+			"d": "не указано количество дней",
+			"b": "не указано количество рабочих дней",
+			"w": "не указаны дни недели",
+			"m": "не указаны дни месяца",
+			"h": "не указано количество часов",
+			"n": "не указано количество минут",
+		}
+		return repeatRule{}, &ruleError{msg: missing[r.kind], pos: end}
+	}
+	if len(args) > allowed {
+		return repeatRule{}, &ruleError{msg: "лишняя часть правила", token: args[allowed].text, pos: args[allowed].pos}
+	}
+
+	var err error
+	switch r.kind {
+	case "d", "b":
+		r.days, err = parseRuleInt(args[0], 1, maxRuleDays,
+			fmt.Sprintf("количество дней должно быть от 1 до %d", maxRuleDays))
+	case "w":
+		r.weekdays, err = parseRuleWeekdays(args[0])
+	case "m":
+		r.monthDays, err = parseRuleList(args[0], func(t ruleToken) (int, error) {
+			day, err := parseRuleInt(t, -2, 31, "день месяца должен быть от 1 до 31, -1 или -2")
+			if err == nil && day == 0 {
+				err = &ruleError{msg: "день месяца должен быть от 1 до 31, -1 или -2", token: t.text, pos: t.pos}
+			}
+			return day, err
+		})
+	case "h", "n":
+		err = r.parseIntraday(args)
+	}
+	if err != nil {
+		return repeatRule{}, err
+	}
+
+	if (r.kind == "w" || r.kind == "m") && len(args) == 2 {
+		r.months, err = parseRuleList(args[1], func(t ruleToken) (int, error) {
+			return parseRuleInt(t, 1, 12, "месяц должен быть от 1 до 12")
+		})
+		if err != nil {
+			return repeatRule{}, err
+		}
+	}
+
+	if r.kind == "m" && !r.canFire() {
+		return repeatRule{}, &ruleError{msg: "правило никогда не срабатывает: в указанных месяцах нет таких дней",
+			token: args[0].text, pos: args[0].pos}
+	}
+	return r, nil
+}
+
+// parseIntraday разбирает части правила h или n: интервал, необязательное окно
+// 09:00-18:00 и необязательные дни недели 1-7
+func (r *repeatRule) parseIntraday(args []ruleToken) error {
+	unit, limit := time.Hour, 24
+	if r.kind == "n" {
+		unit, limit = time.Minute, 24*60
+	}
+	count, err := parseRuleInt(args[0], 1, limit, fmt.Sprintf("интервал должен быть от 1 до %d", limit))
+	if err != nil {
+		return err
+	}
+	r.interval = time.Duration(count) * unit
+
+	rest := args[1:]
+	if len(rest) > 0 && strings.Contains(rest[0].text, ":") {
+		if err := r.parseWindow(rest[0]); err != nil {
+			return err
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		r.weekdays, err = parseRuleList(rest[0], func(t ruleToken) (rruleWeekday, error) {
+			day, err := parseRuleInt(t, 1, 7, "день недели должен быть от 1 до 7")
+			return rruleWeekday{day: time.Weekday(day % 7)}, err
+		})
+		if err != nil {
+			return err
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return &ruleError{msg: "лишняя часть правила", token: rest[0].text, pos: rest[0].pos}
+	}
+	return nil
+}
+
+// parseWindow разбирает окно повторений вида 09:00-18:00
+func (r *repeatRule) parseWindow(t ruleToken) error {
+	from, to, ok := strings.Cut(t.text, "-")
+	if !ok {
+		return &ruleError{msg: "ожидается окно вида 09:00-18:00", token: t.text, pos: t.pos}
+	}
+	var err error
+	if r.windowFrom, err = parseRuleClock(ruleToken{text: from, pos: t.pos}); err != nil {
+		return err
+	}
+	if r.windowTo, err = parseRuleClock(ruleToken{text: to, pos: t.pos + len([]rune(from)) + 1}); err != nil {
+		return err
+	}
+	if !r.windowFrom.Before(r.windowTo) {
+		return &ruleError{msg: "начало окна должно быть раньше его конца", token: t.text, pos: t.pos}
+	}
+	r.hasWindow = true
+	return nil
+}
+
+// parseRuleClock разбирает время HH:MM
+func parseRuleClock(t ruleToken) (time.Time, error) {
+	clock, err := time.Parse("15:04", t.text)
+	if err != nil || len(t.text) != 5 {
+		return time.Time{}, &ruleError{msg: "ожидается время HH:MM", token: t.text, pos: t.pos}
+	}
+	return clock, nil
+}
+
+// intraday сообщает, повторяется ли правило несколько раз в день (h и n)
+func (r repeatRule) intraday() bool {
+	return r.kind == "h" || r.kind == "n"
+}
+
+// isIntradayRule сообщает, что правило — правильное правило h или n
+func isIntradayRule(rule string) bool {
+	r, err := parseRule(rule)
+	return err == nil && r.intraday()
+}
+
+// allowsDay проверяет, разрешён ли день недели date правилом h или n
+func (r repeatRule) allowsDay(date time.Time) bool {
+	if len(r.weekdays) == 0 {
+		return true
+	}
+	for _, wd := range r.weekdays {
+		if wd.day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// stepAt вычисляет следующий после момента t момент срабатывания правила h или n в часовом поясе loc:
+// через интервал, а если он выходит за окно или попадает на неразрешённый день недели —
+// в начале окна (или в полночь, если окна нет) ближайшего разрешённого дня
+func (r repeatRule) stepAt(t time.Time, loc *time.Location) time.Time {
+	next := t.Add(r.interval).In(loc)
+	clock := next.Hour()*60 + next.Minute()
+	inWindow := !r.hasWindow || clock >= minuteOfDay(r.windowFrom) && clock <= minuteOfDay(r.windowTo)
+	if r.allowsDay(next) && inWindow {
+		return next
+	}
+
+	day := time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, time.UTC)
+	// До начала окна в разрешённый день повторение переносится на начало окна того же дня
+	if !r.allowsDay(day) || !r.hasWindow || clock > minuteOfDay(r.windowTo) {
+		day = day.AddDate(0, 0, 1)
+		for !r.allowsDay(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+	return localTime(day, r.windowFrom, loc)
+}
+
+// minuteOfDay возвращает число минут от начала суток до времени clock
+func minuteOfDay(clock time.Time) int {
+	return clock.Hour()*60 + clock.Minute()
+}
+
+// canFire проверяет, что хотя бы один день правила m есть хотя бы в одном из его месяцев
+func (r repeatRule) canFire() bool {
+	for month := 1; month <= 12; month++ {
+		if len(r.months) > 0 && !contains(r.months, month) {
+			continue
+		}
+		for _, day := range r.monthDays {
+			// Последний и предпоследний день есть в любом месяце
+			if day < 0 || day <= monthLength[month] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitRule делит символы правила на части по разделителю sep. Позиции частей
+// отсчитываются от offset. С keepEmpty пустые части (1,,2) сохраняются, чтобы их отвергнуть
+func splitRule(runes []rune, offset int, sep func(rune) bool, keepEmpty bool) []ruleToken {
+	var tokens []ruleToken
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && !sep(runes[i]) {
+			continue
+		}
+		if i > start || keepEmpty {
+			tokens = append(tokens, ruleToken{text: string(runes[start:i]), pos: offset + start})
+		}
+		start = i + 1
+	}
+	return tokens
+}
+
+// parseRuleList разбирает список через запятую, применяя parse к каждому элементу
+func parseRuleList[T any](t ruleToken, parse func(ruleToken) (T, error)) ([]T, error) {
+	isComma := func(r rune) bool { return r == ',' }
+	var result []T
+	for _, item := range splitRule([]rune(t.text), t.pos, isComma, true) {
+		if item.text == "" {
+			return nil, &ruleError{msg: "пустой элемент списка", pos: item.pos}
+		}
+		value, err := parse(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// parseRuleInt разбирает целое число из диапазона min..max, иначе возвращает ошибку msg
+func parseRuleInt(t ruleToken, min, max int, msg string) (int, error) {
+	value, err := strconv.Atoi(t.text)
+	if err != nil {
+		return 0, &ruleError{msg: "ожидается число", token: t.text, pos: t.pos}
+	}
+	if value < min || value > max {
+		return 0, &ruleError{msg: msg, token: t.text, pos: t.pos}
+	}
+	return value, nil
+}
+
+// parseRuleWeekdays разбирает дни недели правила w: 1-7 или день#номер — n-й такой день
+// в месяце (2#2 — второй вторник, 5#-1 — последняя пятница)
+func parseRuleWeekdays(t ruleToken) ([]rruleWeekday, error) {
+	return parseRuleList(t, func(item ruleToken) (rruleWeekday, error) {
+		dayPart, ordPart, hasOrd := strings.Cut(item.text, "#")
+		day, err := parseRuleInt(ruleToken{text: dayPart, pos: item.pos}, 1, 7, "день недели должен быть от 1 до 7")
+		if err != nil {
+			return rruleWeekday{}, err
+		}
+		wd := rruleWeekday{day: time.Weekday(day % 7)}
+		if hasOrd {
+			ord := ruleToken{text: ordPart, pos: item.pos + len([]rune(dayPart)) + 1}
+			msg := "номер дня недели в месяце должен быть от 1 до 5 или от -5 до -1"
+			if wd.ord, err = parseRuleInt(ord, -5, 5, msg); err != nil {
+				return rruleWeekday{}, err
+			}
+			if wd.ord == 0 {
+				return rruleWeekday{}, &ruleError{msg: msg, token: ord.text, pos: ord.pos}
+			}
+		}
+		return wd, nil
+	})
+}
+
+// toRRule возвращает правило в виде RRULE; правило b в нём не выражается
+func (r repeatRule) toRRule() (rrule, error) {
+	switch r.kind {
+	case "d":
+		return rrule{freq: "DAILY", interval: r.days}, nil
+	case "y":
+		return rrule{freq: "YEARLY", interval: 1}, nil
+	case "w":
+		// С порядковыми номерами дней правило ежемесячное, без них — еженедельное
+		weekly := rrule{freq: "WEEKLY", interval: 1, byDay: r.weekdays, byMonth: r.months}
+		for _, wd := range r.weekdays {
+			if wd.ord != 0 {
+				weekly.freq = "MONTHLY"
+			}
+		}
+		return weekly, nil
+	case "m":
+		return rrule{freq: "MONTHLY", interval: 1, byMonthDay: r.monthDays, byMonth: r.months}, nil
+	}
+	// Правила b, h и n в поддерживаемом подмножестве RRULE не выражаются
+	return rrule{}, fmt.Errorf("правило %s нельзя записать в формате RRULE", r.kind)
+}
+
+// step вычисляет следующую после date дату по правилу. Правило b считает рабочие дни
+// по календарю cal
+func (r repeatRule) step(cal *calendar, date time.Time) (time.Time, error) {
+	switch r.kind {
+	case "d":
+		return date.AddDate(0, 0, r.days), nil
+
+	case "b":
+		return cal.addWorkdays(date, r.days)
+
+	case "y":
+		return date.AddDate(1, 0, 0), nil
+
+	case "h", "n":
+		// С точностью до дня правило срабатывает в каждый разрешённый день недели
+		next := date.AddDate(0, 0, 1)
+		for !r.allowsDay(next) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, nil
+
+	case "w":
+		weekly, _ := r.toRRule()
+		next, _, err := weekly.after(date, date)
+		return next, err
+
+	case "m":
+		// Перебираем месяцы начиная с текущего и берём самый ранний подходящий день после date.
+		// Дни, которых нет в месяце (31 апреля), пропускаются; parseRule гарантирует, что
+		// подходящий день найдётся, а ограничение защищает от ошибок в этой гарантии
+		month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 12*rruleHorizon; i, month = i+1, month.AddDate(0, 1, 0) {
+			if len(r.months) > 0 && !contains(r.months, int(month.Month())) {
+				continue
+			}
+			var next time.Time
+			for _, day := range r.monthDays {
+				candidate, ok := resolveDay(month.Year(), int(month.Month()), day)
+				if ok && candidate.After(date) && (next.IsZero() || candidate.Before(next)) {
+					next = candidate
+				}
+			}
+			if !next.IsZero() {
+				return next, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("правило не срабатывает в ближайшие %d лет", rruleHorizon)
+	}
+	return time.Time{}, fmt.Errorf("неизвестное правило: %s", r.kind)
+}
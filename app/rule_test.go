@@ -0,0 +1,103 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRuleErrors(t *testing.T) {
+	tbl := []struct {
+		rule string
+		pos  int
+	}{
+		{"x 1", 1},
+		{"d", 2},
+		{"d 401", 3},
+		{"d 5 7", 5},
+		{"d 1a", 3},
+		{"y 1", 3},
+		{"w 8,4,5", 3},
+		{"w 1,2,", 7},
+		{"w 2#0", 5},
+		{"w 2#6", 5},
+		{"w 1 13", 5},
+		{"m 40,11,19", 3},
+		{"m -2,-3", 6},
+		{"m 1,,2", 5},
+		{"m 0", 3},
+		{"m 31 2", 3},
+		{"m 30,31 2", 3},
+		{"m 31 4,6,9,11", 3},
+		{"m 5 1,0", 7},
+		{"m  15  1,ф", 10},
+		{"h", 2},
+		{"h 25", 3},
+		{"n 0", 3},
+		{"h 2 9:00-18:00", 5},
+		{"h 2 09:00-8:00", 11},
+		{"h 2 18:00-09:00", 5},
+		{"h 2 09:00", 5},
+		{"h 2 09:00-18:00 1,8", 19},
+		{"h 2 1,2 09:00-18:00", 9},
+		{"n 5 1 2", 7},
+	}
+	for _, v := range tbl {
+		_, err := parseRule(v.rule)
+		var ruleErr *ruleError
+		if assert.ErrorAs(t, err, &ruleErr, v.rule) {
+			assert.Equal(t, v.pos, ruleErr.pos, v.rule)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, rule := range []string{"d 1", "d 400", "b 3", "y", "w 7", "w 2#2,5#-1 3,9",
+		"m -1", "m -2,31 2", "m 29 2", "m 07,19 05,6", " m   1 ", "h 24", "n 1440", "h 1 1,7",
+		"n 30 08:00-20:00"} {
+		_, err := parseRule(rule)
+		assert.NoError(t, err, rule)
+	}
+
+	// 29 февраля срабатывает только в високосные годы
+	r, err := parseRule("m 29 2")
+	assert.NoError(t, err)
+	next, err := r.step(&calendar{}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "20280229", next.Format("20060102"))
+}
+
+func TestStepAt(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	at := func(s string) time.Time {
+		moment, err := time.ParseInLocation("20060102 15:04", s, loc)
+		assert.NoError(t, err)
+		return moment
+	}
+
+	tbl := []struct {
+		rule string
+		from string
+		want string
+	}{
+		{"h 2", "20250106 23:00", "2025-01-07T01:00:00-05:00"},
+		{"n 45", "20250106 09:30", "2025-01-06T10:15:00-05:00"},
+		{"h 2 09:00-18:00", "20250106 16:00", "2025-01-06T18:00:00-05:00"},
+		{"h 2 09:00-18:00", "20250106 17:00", "2025-01-07T09:00:00-05:00"},
+		{"h 3 09:00-18:00", "20250106 05:00", "2025-01-06T09:00:00-05:00"},
+		// Пятница, следующий разрешённый день — понедельник
+		{"h 2 09:00-18:00 1,2,3,4,5", "20250110 17:00", "2025-01-13T09:00:00-05:00"},
+		{"h 1 6", "20250111 23:30", "2025-01-18T00:00:00-05:00"},
+		// Интервал отсчитывается по реальному времени: при переводе часов вперёд
+		// через час после 01:30 на часах 03:30
+		{"h 1", "20250309 01:30", "2025-03-09T03:30:00-04:00"},
+		{"h 1 01:00-05:00", "20251102 01:30", "2025-11-02T01:30:00-05:00"},
+	}
+	for _, v := range tbl {
+		r, err := parseRule(v.rule)
+		assert.NoError(t, err, v.rule)
+		assert.Equal(t, v.want, r.stepAt(at(v.from), loc).Format(time.RFC3339), v.rule+" "+v.from)
+	}
+}
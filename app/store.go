@@ -0,0 +1,98 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTaskNotFound возвращается хранилищем, если у пользователя нет задачи с таким id
+var ErrTaskNotFound = errors.New("задача не найдена")
+
+// timestamp переводит момент в формат, в котором хранятся completed_at, archived_at, deleted_at,
+// changed_at и created_at журналов: UTC, RFC 3339 без долей секунды. У таких строк одинаковая
+// длина и зона Z, поэтому запросы сравнивают их как строки, и порядок строк совпадает с порядком моментов
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Состояния задачи: активная, выполненная и перенесённая в архив.
+// Выполненные и архивные задачи хранятся, пока их не удалит очистка архива
+const (
+	statusActive   = "active"
+	statusDone     = "done"
+	statusArchived = "archived"
+	statusAll      = "all" // только для TaskFilter: задачи в любом состоянии
+)
+
+// TaskFilter ограничивает выборку задач в TaskStore.List
+type TaskFilter struct {
+	Date   string // только задачи на эту дату в формате YYYYMMDD
+	Search string // подстрока заголовка или комментария без учёта регистра
+	Status string // состояние задач или statusAll, пустое — только активные
+	Limit  int    // максимальное количество задач, 0 — без ограничения
+}
+
+// CompletionFilter ограничивает выборку записей журнала выполнения в TaskStore.History
+type CompletionFilter struct {
+	TaskID string    // только выполнения этой задачи
+	From   time.Time // выполненные не раньше этого момента
+	To     time.Time // выполненные раньше этого момента
+	Limit  int       // максимальное количество записей, 0 — без ограничения
+}
+
+// ChangeFilter ограничивает выборку записей журнала изменений в TaskStore.Changes
+type ChangeFilter struct {
+	TaskID string // только изменения этой задачи
+	Field  string // только изменения этого поля
+	Limit  int    // максимальное количество записей, 0 — без ограничения
+}
+
+// TaskStore хранит задачи пользователей. Все методы, кроме PurgeArchived и PurgeDeleted,
+// работают только с задачами пользователя userID: чужие задачи для них не существуют
+// и дают ErrTaskNotFound. Get, Update, Complete и Archive работают только с активными задачами,
+// а задачи в корзине видны только Trash, Restore и Purge. Create, Update, Delete и Complete
+// в том же изменении записывают операцию в журнал, чтобы её можно было отменить через Undo,
+// а Update ещё и записывает старые и новые значения изменённых полей в журнал изменений.
+// Задачи проверяются до передачи в хранилище, дата хранится в формате YYYYMMDD
+type TaskStore interface {
+	// Create сохраняет новую задачу и возвращает её id
+	Create(userID int64, task Task) (int64, error)
+	// Get возвращает задачу по id
+	Get(userID int64, id string) (Task, error)
+	// Update заменяет дату, заголовок, комментарий, правило повторения, его ограничения,
+	// даты-исключения, перенос с выходных, дату по правилу до переноса, время, часовой пояс
+	// и режим повторения задачи task.ID
+	Update(userID int64, task Task) error
+	// Delete переносит задачу в любом состоянии в корзину в момент at
+	Delete(userID int64, id string, at time.Time) error
+	// List возвращает задачи по возрастанию даты и времени
+	List(userID int64, filter TaskFilter) ([]Task, error)
+	// Complete сохраняет задачу task.ID после выполнения: переносит её на дату task.Date
+	// (по правилу — task.RuleDate) и время task.Time с правилом task.Repeat и счётчиком
+	// task.Count, а если дата пустая (повторений больше нет), переводит в состояние done.
+	// В том же изменении done записывается в журнал выполнения
+	Complete(userID int64, task Task, done Completion) error
+	// Archive переносит задачу в архив в момент at
+	Archive(userID int64, id string, at time.Time) error
+	// PurgeArchived удаляет у всех пользователей выполненные и архивные задачи,
+	// перешедшие в это состояние раньше before, и возвращает их число
+	PurgeArchived(before time.Time) (int64, error)
+	// Trash возвращает задачи из корзины, начиная с последних удалённых
+	Trash(userID int64) ([]Task, error)
+	// Restore возвращает задачу из корзины в прежнем состоянии
+	Restore(userID int64, id string) error
+	// Purge удаляет задачу из корзины насовсем
+	Purge(userID int64, id string) error
+	// PurgeDeleted удаляет насовсем у всех пользователей задачи, перенесённые в корзину
+	// раньше before, и возвращает их число
+	PurgeDeleted(before time.Time) (int64, error)
+	// History возвращает записи журнала выполнения, начиная с последних
+	History(userID int64, filter CompletionFilter) ([]Completion, error)
+	// Undo отменяет n последних операций из журнала, начиная с последней, удаляет их
+	// из журнала и возвращает. Операции отменяются все вместе или ни одна. Задачу, удалённую
+	// насовсем, отмена не восстанавливает: такая операция возвращается с Skipped, но запись
+	// журнала выполнения, добавленная операцией done, всё равно удаляется
+	Undo(userID int64, n int) ([]Operation, error)
+	// Changes возвращает записи журнала изменений, начиная с последних
+	Changes(userID int64, filter ChangeFilter) ([]TaskChange, error)
+}
@@ -0,0 +1,424 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore хранит задачи в памяти процесса. Подходит для тестов
+// и запуска без файла базы данных
+type memoryStore struct {
+	mu               sync.Mutex
+	lastID           int64
+	tasks            map[int64]memoryTask
+	lastCompletionID int64
+	completions      []memoryCompletion
+	lastOperationID  int64
+	operations       []memoryOperation
+	changes          []TaskChange
+}
+
+type memoryTask struct {
+	userID int64
+	task   Task
+}
+
+type memoryCompletion struct {
+	userID int64
+	done   Completion
+}
+
+type memoryOperation struct {
+	userID int64
+	op     Operation
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{tasks: make(map[int64]memoryTask)}
+}
+
+// find возвращает задачу пользователя по строковому id. Вызывается под блокировкой
+func (s *memoryStore) find(userID int64, id string) (int64, memoryTask, error) {
+	key, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, memoryTask{}, ErrTaskNotFound
+	}
+	stored, ok := s.tasks[key]
+	if !ok || stored.userID != userID {
+		return 0, memoryTask{}, ErrTaskNotFound
+	}
+	return key, stored, nil
+}
+
+// findActive возвращает активную задачу пользователя не из корзины по строковому id.
+// Вызывается под блокировкой
+func (s *memoryStore) findActive(userID int64, id string) (int64, memoryTask, error) {
+	key, stored, err := s.find(userID, id)
+	if err == nil && (stored.task.Status != statusActive || stored.task.DeletedAt != "") {
+		return 0, memoryTask{}, ErrTaskNotFound
+	}
+	return key, stored, err
+}
+
+// findDeleted возвращает задачу пользователя из корзины по строковому id. Вызывается под блокировкой
+func (s *memoryStore) findDeleted(userID int64, id string) (int64, memoryTask, error) {
+	key, stored, err := s.find(userID, id)
+	if err == nil && stored.task.DeletedAt == "" {
+		return 0, memoryTask{}, ErrTaskNotFound
+	}
+	return key, stored, err
+}
+
+// record записывает операцию в журнал и оставляет в нём последние maxOperations
+// операций пользователя. Вызывается под блокировкой
+func (s *memoryStore) record(userID int64, op Operation) {
+	s.lastOperationID++
+	op.ID = strconv.FormatInt(s.lastOperationID, 10)
+	s.operations = append(s.operations, memoryOperation{userID: userID, op: op})
+
+	count := 0
+	for i := len(s.operations) - 1; i >= 0; i-- {
+		if s.operations[i].userID != userID {
+			continue
+		}
+		if count++; count > maxOperations {
+			s.operations = append(s.operations[:i], s.operations[i+1:]...)
+		}
+	}
+}
+
+// recordChanges записывает изменения полей задачи в журнал изменений. Вызывается под блокировкой
+func (s *memoryStore) recordChanges(userID int64, changes []TaskChange) {
+	for _, change := range changes {
+		change.ID = strconv.Itoa(len(s.changes) + 1)
+		change.UserID = strconv.FormatInt(userID, 10)
+		s.changes = append(s.changes, change)
+	}
+}
+
+func (s *memoryStore) Create(userID int64, task Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	task.ID = strconv.FormatInt(s.lastID, 10)
+	task.Status, task.ArchivedAt, task.DeletedAt = statusActive, "", ""
+	s.tasks[s.lastID] = memoryTask{userID: userID, task: task}
+	s.record(userID, newOperation(opCreate, task.ID, task.Title, Task{}))
+	return s.lastID, nil
+}
+
+func (s *memoryStore) Get(userID int64, id string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, stored, err := s.findActive(userID, id)
+	return stored.task, err
+}
+
+func (s *memoryStore) Update(userID int64, task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, stored, err := s.findActive(userID, task.ID)
+	if err != nil {
+		return err
+	}
+	task.ID = stored.task.ID
+	task.Status, task.ArchivedAt, task.DeletedAt = stored.task.Status, stored.task.ArchivedAt, ""
+	s.tasks[key] = memoryTask{userID: userID, task: task}
+	s.record(userID, newOperation(opUpdate, stored.task.ID, stored.task.Title, stored.task))
+	s.recordChanges(userID, taskChanges(stored.task, task, time.Now()))
+	return nil
+}
+
+func (s *memoryStore) Delete(userID int64, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, stored, err := s.find(userID, id)
+	if err != nil || stored.task.DeletedAt != "" {
+		return ErrTaskNotFound
+	}
+	s.record(userID, newOperation(opDelete, stored.task.ID, stored.task.Title, stored.task))
+	stored.task.DeletedAt = timestamp(at)
+	s.tasks[key] = stored
+	return nil
+}
+
+func (s *memoryStore) List(userID int64, filter TaskFilter) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search := strings.ToLower(filter.Search)
+	var tasks []Task
+	for _, stored := range s.tasks {
+		task := stored.task
+		if stored.userID != userID || task.DeletedAt != "" || (filter.Date != "" && task.Date != filter.Date) {
+			continue
+		}
+		if status := filter.Status; status != statusAll && task.Status != status &&
+			!(status == "" && task.Status == statusActive) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(task.Title), search) &&
+			!strings.Contains(strings.ToLower(task.Comment), search) {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Date != tasks[j].Date {
+			return tasks[i].Date < tasks[j].Date
+		}
+		if tasks[i].Time != tasks[j].Time {
+			return tasks[i].Time < tasks[j].Time
+		}
+		a, _ := strconv.ParseInt(tasks[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(tasks[j].ID, 10, 64)
+		return a < b
+	})
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+func (s *memoryStore) Complete(userID int64, task Task, done Completion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, stored, err := s.findActive(userID, task.ID)
+	if err != nil {
+		return err
+	}
+	s.lastCompletionID++
+	done.ID = strconv.FormatInt(s.lastCompletionID, 10)
+	s.completions = append(s.completions, memoryCompletion{userID: userID, done: done})
+	op := newOperation(opDone, stored.task.ID, stored.task.Title, stored.task)
+	op.CompletionID = done.ID
+	s.record(userID, op)
+	if task.Date == "" {
+		stored.task.Status, stored.task.ArchivedAt = statusDone, done.CompletedAt
+		stored.task.Count = task.Count
+		s.tasks[key] = stored
+		return nil
+	}
+	stored.task.Date = task.Date
+	stored.task.RuleDate = task.RuleDate
+	stored.task.Time = task.Time
+	stored.task.Repeat = task.Repeat
+	stored.task.Count = task.Count
+	s.tasks[key] = stored
+	return nil
+}
+
+func (s *memoryStore) Archive(userID int64, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, stored, err := s.findActive(userID, id)
+	if err != nil {
+		return err
+	}
+	stored.task.Status, stored.task.ArchivedAt = statusArchived, timestamp(at)
+	s.tasks[key] = stored
+	return nil
+}
+
+func (s *memoryStore) PurgeArchived(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, stored := range s.tasks {
+		if stored.task.Status == statusActive {
+			continue
+		}
+		archivedAt, err := time.Parse(time.RFC3339, stored.task.ArchivedAt)
+		if err != nil {
+			return purged, err
+		}
+		if archivedAt.Before(before) {
+			delete(s.tasks, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *memoryStore) Trash(userID int64) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []Task
+	for _, stored := range s.tasks {
+		if stored.userID == userID && stored.task.DeletedAt != "" {
+			tasks = append(tasks, stored.task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].DeletedAt != tasks[j].DeletedAt {
+			return tasks[i].DeletedAt > tasks[j].DeletedAt
+		}
+		a, _ := strconv.ParseInt(tasks[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(tasks[j].ID, 10, 64)
+		return a > b
+	})
+	return tasks, nil
+}
+
+func (s *memoryStore) Restore(userID int64, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, stored, err := s.findDeleted(userID, id)
+	if err != nil {
+		return err
+	}
+	stored.task.DeletedAt = ""
+	s.tasks[key] = stored
+	return nil
+}
+
+func (s *memoryStore) Purge(userID int64, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, _, err := s.findDeleted(userID, id)
+	if err != nil {
+		return err
+	}
+	delete(s.tasks, key)
+	return nil
+}
+
+func (s *memoryStore) PurgeDeleted(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, stored := range s.tasks {
+		if stored.task.DeletedAt == "" {
+			continue
+		}
+		deletedAt, err := time.Parse(time.RFC3339, stored.task.DeletedAt)
+		if err != nil {
+			return purged, err
+		}
+		if deletedAt.Before(before) {
+			delete(s.tasks, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *memoryStore) History(userID int64, filter CompletionFilter) ([]Completion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []Completion
+	// Записи добавляются по порядку выполнения, поэтому последние — в конце
+	for i := len(s.completions) - 1; i >= 0; i-- {
+		stored := s.completions[i]
+		if stored.userID != userID || (filter.TaskID != "" && stored.done.TaskID != filter.TaskID) {
+			continue
+		}
+		completedAt, err := time.Parse(time.RFC3339, stored.done.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if (!filter.From.IsZero() && completedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !completedAt.Before(filter.To)) {
+			continue
+		}
+		history = append(history, stored.done)
+		if filter.Limit > 0 && len(history) == filter.Limit {
+			break
+		}
+	}
+	return history, nil
+}
+
+func (s *memoryStore) Undo(userID int64, n int) ([]Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Как транзакция в SQLite: при ошибке задачи и журналы возвращаются в состояние до отмены
+	tasks, completions, operations := maps.Clone(s.tasks), slices.Clone(s.completions), slices.Clone(s.operations)
+	changes := slices.Clone(s.changes)
+	ops, err := s.undo(userID, n)
+	if err != nil {
+		s.tasks, s.completions, s.operations, s.changes = tasks, completions, operations, changes
+		return nil, err
+	}
+	return ops, nil
+}
+
+// undo отменяет n последних операций пользователя. Вызывается под блокировкой
+func (s *memoryStore) undo(userID int64, n int) ([]Operation, error) {
+	var ops []Operation
+	for i := len(s.operations) - 1; i >= 0 && len(ops) < n; i-- {
+		stored := s.operations[i]
+		if stored.userID != userID {
+			continue
+		}
+		s.operations = append(s.operations[:i], s.operations[i+1:]...)
+
+		key, err := strconv.ParseInt(stored.op.TaskID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("неправильный id задачи в операции %s: %s", stored.op.ID, stored.op.TaskID)
+		}
+		if stored.op.Kind == opDone {
+			s.completions = slices.DeleteFunc(s.completions, func(c memoryCompletion) bool {
+				return c.userID == userID && c.done.ID == stored.op.CompletionID
+			})
+		}
+		// Задача, удалённая насовсем, не восстанавливается
+		current, ok := s.tasks[key]
+		stored.op.Skipped = !ok || current.userID != userID
+		ops = append(ops, stored.op)
+		switch {
+		case stored.op.Skipped:
+		case stored.op.Kind == opCreate:
+			delete(s.tasks, key)
+		default:
+			// Возврат задачи в прежнее состояние — такое же изменение полей, как Update
+			task := undoneTask(stored.op, current.task)
+			s.recordChanges(userID, taskChanges(current.task, task, time.Now()))
+			s.tasks[key] = memoryTask{userID: userID, task: task}
+		}
+	}
+	return ops, nil
+}
+
+// Changes возвращает журнал изменений без поля Login: учётные записи хранятся только в базе,
+// и хранилище в памяти знает лишь номер пользователя
+func (s *memoryStore) Changes(userID int64, filter ChangeFilter) ([]TaskChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := strconv.FormatInt(userID, 10)
+	var changes []TaskChange
+	// Записи добавляются по порядку изменения, поэтому последние — в конце
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		change := s.changes[i]
+		if change.UserID != user || (filter.TaskID != "" && change.TaskID != filter.TaskID) ||
+			(filter.Field != "" && change.Field != filter.Field) {
+			continue
+		}
+		changes = append(changes, change)
+		if filter.Limit > 0 && len(changes) == filter.Limit {
+			break
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,602 @@
+package app
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	// Встроенные LOWER и LIKE в SQLite не учитывают регистр только для латиницы,
+	// поэтому для поиска по кириллице регистрируем свою функцию приведения к нижнему регистру
+	sqlite.MustRegisterDeterministicScalarFunction("utf8_lower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			if s, ok := args[0].(string); ok {
+				return strings.ToLower(s), nil
+			}
+			return args[0], nil
+		})
+}
+
+// sqliteStore хранит задачи в таблице scheduler базы SQLite
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(db *sql.DB) *sqliteStore {
+	return &sqliteStore{db: db}
+}
+
+// taskColumns — столбцы scheduler в порядке, в котором их читает scanTask
+const taskColumns = `id, date, title, comment, repeat, repeat_count, repeat_until, exdates, shift, due_time, tz, repeat_mode, status, archived_at, deleted_at, rule_date`
+
+// scanTask читает задачу из строки результата запроса по столбцам taskColumns
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var task Task
+	var count int64
+	err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &count, &task.Until, &task.Exdates, &task.Shift, &task.Time, &task.TZ, &task.Mode,
+		&task.Status, &task.ArchivedAt, &task.DeletedAt, &task.RuleDate)
+	if count > 0 {
+		task.Count = strconv.FormatInt(count, 10)
+	}
+	return task, err
+}
+
+// repeatCount переводит число повторений задачи в значение столбца repeat_count
+func repeatCount(task Task) int64 {
+	count, _ := strconv.ParseInt(task.Count, 10, 64)
+	return count
+}
+
+func (s *sqliteStore) Create(userID int64, task Task) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO scheduler (date, title, comment, repeat, repeat_count, repeat_until, exdates, shift, due_time, tz, repeat_mode, rule_date, user_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+	result, err := tx.Exec(query, task.Date, task.Title, task.Comment, task.Repeat,
+		repeatCount(task), task.Until, task.Exdates, task.Shift, task.Time, task.TZ, task.Mode, task.RuleDate, userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сохранения задачи: %v", err)
+	}
+
+	// Получаем ID последней вставленной записи
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения ID задачи: %v", err)
+	}
+
+	op := newOperation(opCreate, strconv.FormatInt(id, 10), task.Title, Task{})
+	if err := recordOperation(tx, userID, op); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения задачи: %v", err)
+	}
+	return id, nil
+}
+
+func (s *sqliteStore) Get(userID int64, id string) (Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ? AND user_id = ? AND status = 'active' AND deleted_at = ''`
+	task, err := scanTask(s.db.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("ошибка выполнения запроса: %v", err)
+	}
+	return task, nil
+}
+
+func (s *sqliteStore) Update(userID int64, task Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := snapshot(tx, userID, task.ID, `status = 'active' AND deleted_at = ''`)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE scheduler
+		SET date = ?, title = ?, comment = ?, repeat = ?,
+			repeat_count = ?, repeat_until = ?, exdates = ?, shift = ?, due_time = ?, tz = ?, repeat_mode = ?, rule_date = ?
+		WHERE id = ? AND user_id = ?;
+	`
+	_, err = tx.Exec(query, task.Date, task.Title, task.Comment, task.Repeat,
+		repeatCount(task), task.Until, task.Exdates, task.Shift, task.Time, task.TZ, task.Mode, task.RuleDate, task.ID, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления задачи: %v", err)
+	}
+
+	if err := recordOperation(tx, userID, newOperation(opUpdate, before.ID, before.Title, before)); err != nil {
+		return err
+	}
+	if err := recordChanges(tx, userID, taskChanges(before, task, time.Now())); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка обновления задачи: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Delete(userID int64, id string, at time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := snapshot(tx, userID, id, `deleted_at = ''`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE scheduler
+		SET deleted_at = ?
+		WHERE id = ? AND user_id = ?;
+	`, timestamp(at), id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления задачи: %v", err)
+	}
+
+	if err := recordOperation(tx, userID, newOperation(opDelete, before.ID, before.Title, before)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка удаления задачи: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) List(userID int64, filter TaskFilter) ([]Task, error) {
+	query := `
+        SELECT ` + taskColumns + `
+        FROM scheduler
+        WHERE user_id = ? AND deleted_at = ''
+    `
+	args := []any{userID}
+
+	switch filter.Status {
+	case statusAll:
+	case "":
+		query += ` AND status = 'active'`
+	default:
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Date != "" {
+		query += ` AND date = ?`
+		args = append(args, filter.Date)
+	}
+	if filter.Search != "" {
+		// Экранируем спецсимволы LIKE, чтобы искать строку буквально
+		pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(filter.Search))
+		pattern = "%" + pattern + "%"
+		query += ` AND (utf8_lower(title) LIKE ? ESCAPE '\' OR utf8_lower(comment) LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+	query += ` ORDER BY date ASC, due_time ASC, id ASC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	return s.queryTasks(query, args...)
+}
+
+// queryTasks выполняет запрос, выбирающий столбцы taskColumns, и читает найденные задачи
+func (s *sqliteStore) queryTasks(query string, args ...any) ([]Task, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задач: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+	return tasks, nil
+}
+
+func (s *sqliteStore) Complete(userID int64, task Task, done Completion) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := snapshot(tx, userID, task.ID, `status = 'active' AND deleted_at = ''`)
+	if err != nil {
+		return err
+	}
+	if task.Date == "" {
+		// Выполненная задача остаётся с прежней датой, чтобы было видно, на когда она была назначена
+		_, err = tx.Exec(`
+			UPDATE scheduler
+			SET status = 'done', archived_at = ?, repeat_count = ?
+			WHERE id = ? AND user_id = ?;
+		`, done.CompletedAt, repeatCount(task), task.ID, userID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE scheduler
+			SET date = ?, rule_date = ?, due_time = ?, repeat = ?, repeat_count = ?
+			WHERE id = ? AND user_id = ?;
+		`, task.Date, task.RuleDate, task.Time, task.Repeat, repeatCount(task), task.ID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка обновления даты задачи: %v", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO completions (task_id, user_id, title, date, due_time, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`, done.TaskID, userID, done.Title, done.Date, done.Time, done.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал выполнения: %v", err)
+	}
+	completionID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал выполнения: %v", err)
+	}
+
+	op := newOperation(opDone, before.ID, before.Title, before)
+	op.CompletionID = strconv.FormatInt(completionID, 10)
+	if err := recordOperation(tx, userID, op); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка сохранения выполнения: %v", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Archive(userID int64, id string, at time.Time) error {
+	result, err := s.db.Exec(`
+		UPDATE scheduler
+		SET status = 'archived', archived_at = ?
+		WHERE id = ? AND user_id = ? AND status = 'active' AND deleted_at = '';
+	`, timestamp(at), id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка переноса задачи в архив: %v", err)
+	}
+	return checkAffected(result)
+}
+
+func (s *sqliteStore) PurgeArchived(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM scheduler
+		WHERE status IN ('done', 'archived') AND archived_at < ?;
+	`, timestamp(before))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка очистки архива: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteStore) Trash(userID int64) ([]Task, error) {
+	return s.queryTasks(`
+        SELECT `+taskColumns+`
+        FROM scheduler
+        WHERE user_id = ? AND deleted_at != ''
+        ORDER BY deleted_at DESC, id DESC
+    `, userID)
+}
+
+func (s *sqliteStore) Restore(userID int64, id string) error {
+	result, err := s.db.Exec(`
+		UPDATE scheduler
+		SET deleted_at = ''
+		WHERE id = ? AND user_id = ? AND deleted_at != '';
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка восстановления задачи: %v", err)
+	}
+	return checkAffected(result)
+}
+
+func (s *sqliteStore) Purge(userID int64, id string) error {
+	result, err := s.db.Exec(`DELETE FROM scheduler WHERE id = ? AND user_id = ? AND deleted_at != ''`, id, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления задачи: %v", err)
+	}
+	return checkAffected(result)
+}
+
+func (s *sqliteStore) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM scheduler
+		WHERE deleted_at != '' AND deleted_at < ?;
+	`, timestamp(before))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка очистки корзины: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteStore) History(userID int64, filter CompletionFilter) ([]Completion, error) {
+	query := `
+        SELECT id, task_id, title, date, due_time, completed_at
+        FROM completions
+        WHERE user_id = ?
+    `
+	args := []any{userID}
+
+	if filter.TaskID != "" {
+		query += ` AND task_id = ?`
+		args = append(args, filter.TaskID)
+	}
+	if !filter.From.IsZero() {
+		query += ` AND completed_at >= ?`
+		args = append(args, timestamp(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query += ` AND completed_at < ?`
+		args = append(args, timestamp(filter.To))
+	}
+	query += ` ORDER BY completed_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала выполнения: %v", err)
+	}
+	defer rows.Close()
+
+	var history []Completion
+	for rows.Next() {
+		var done Completion
+		if err := rows.Scan(&done.ID, &done.TaskID, &done.Title, &done.Date, &done.Time, &done.CompletedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+		}
+		history = append(history, done)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+	return history, nil
+}
+
+func (s *sqliteStore) Undo(userID int64, n int) ([]Operation, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT id, task_id, kind, title, task, completion_id, created_at
+        FROM operations
+        WHERE user_id = ?
+        ORDER BY id DESC
+        LIMIT ?
+    `, userID, n)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала операций: %v", err)
+	}
+	var ops []Operation
+	for rows.Next() {
+		var op Operation
+		var before string
+		if err := rows.Scan(&op.ID, &op.TaskID, &op.Kind, &op.Title, &before, &op.CompletionID, &op.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+		}
+		if before != "" {
+			if err := json.Unmarshal([]byte(before), &op.Before); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("ошибка чтения операции %s: %v", op.ID, err)
+			}
+		}
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+
+	// Операции отменяются после чтения журнала: пока открыт rows, транзакция занята запросом
+	for i, op := range ops {
+		applied, err := undoOperation(tx, userID, op)
+		if err != nil {
+			return nil, err
+		}
+		ops[i].Skipped = !applied
+		if _, err := tx.Exec(`DELETE FROM operations WHERE id = ?`, op.ID); err != nil {
+			return nil, fmt.Errorf("ошибка удаления операции из журнала: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка отмены операций: %v", err)
+	}
+	return ops, nil
+}
+
+func (s *sqliteStore) Changes(userID int64, filter ChangeFilter) ([]TaskChange, error) {
+	query := `
+        SELECT c.id, c.task_id, c.field, c.old_value, c.new_value, c.user_id, COALESCE(u.login, ''), c.changed_at
+        FROM task_changes c
+        LEFT JOIN users u ON u.id = c.user_id
+        WHERE c.user_id = ?
+    `
+	args := []any{userID}
+
+	if filter.TaskID != "" {
+		query += ` AND c.task_id = ?`
+		args = append(args, filter.TaskID)
+	}
+	if filter.Field != "" {
+		query += ` AND c.field = ?`
+		args = append(args, filter.Field)
+	}
+	query += ` ORDER BY c.id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала изменений: %v", err)
+	}
+	defer rows.Close()
+
+	var changes []TaskChange
+	for rows.Next() {
+		var change TaskChange
+		err := rows.Scan(&change.ID, &change.TaskID, &change.Field, &change.Old, &change.New,
+			&change.UserID, &change.Login, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения данных: %v", err)
+	}
+	return changes, nil
+}
+
+// snapshot возвращает задачу до изменения, если она удовлетворяет условию cond
+func snapshot(tx *sql.Tx, userID int64, id, cond string) (Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ? AND user_id = ? AND ` + cond
+	task, err := scanTask(tx.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("ошибка выполнения запроса: %v", err)
+	}
+	return task, nil
+}
+
+// recordOperation записывает операцию в журнал и оставляет в нём последние
+// maxOperations операций пользователя
+func recordOperation(tx *sql.Tx, userID int64, op Operation) error {
+	var before []byte
+	if op.Kind != opCreate {
+		var err error
+		if before, err = json.Marshal(op.Before); err != nil {
+			return fmt.Errorf("ошибка записи в журнал операций: %v", err)
+		}
+	}
+	completionID, _ := strconv.ParseInt(op.CompletionID, 10, 64)
+	_, err := tx.Exec(`
+		INSERT INTO operations (user_id, task_id, kind, title, task, completion_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
+	`, userID, op.TaskID, op.Kind, op.Title, string(before), completionID, op.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал операций: %v", err)
+	}
+	_, err = tx.Exec(`
+		DELETE FROM operations
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM operations WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		);
+	`, userID, userID, maxOperations)
+	if err != nil {
+		return fmt.Errorf("ошибка очистки журнала операций: %v", err)
+	}
+	return nil
+}
+
+// undoOperation отменяет операцию op: удаляет созданную задачу или возвращает поля задачи,
+// изменённые операцией.
+// Возвращает false, если задачу уже удалили насовсем
+func undoOperation(tx *sql.Tx, userID int64, op Operation) (bool, error) {
+	// Запись журнала выполнения удаляется, даже если задачу уже удалили насовсем
+	if op.Kind == opDone {
+		if _, err := tx.Exec(`DELETE FROM completions WHERE id = ? AND user_id = ?`, op.CompletionID, userID); err != nil {
+			return false, fmt.Errorf("ошибка удаления записи журнала выполнения: %v", err)
+		}
+	}
+
+	if op.Kind == opCreate {
+		result, err := tx.Exec(`DELETE FROM scheduler WHERE id = ? AND user_id = ?`, op.TaskID, userID)
+		if err != nil {
+			return false, fmt.Errorf("ошибка отмены создания задачи: %v", err)
+		}
+		// Задача, удалённая насовсем, не восстанавливается
+		err = checkAffected(result)
+		if errors.Is(err, ErrTaskNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	current, err := snapshot(tx, userID, op.TaskID, `1 = 1`)
+	if errors.Is(err, ErrTaskNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	task := undoneTask(op, current)
+	_, err = tx.Exec(`
+		UPDATE scheduler
+		SET date = ?, title = ?, comment = ?, repeat = ?, repeat_count = ?, repeat_until = ?, exdates = ?,
+			shift = ?, due_time = ?, tz = ?, repeat_mode = ?, status = ?, archived_at = ?, deleted_at = ?, rule_date = ?
+		WHERE id = ? AND user_id = ?;
+	`, task.Date, task.Title, task.Comment, task.Repeat, repeatCount(task), task.Until, task.Exdates,
+		task.Shift, task.Time, task.TZ, task.Mode, task.Status, task.ArchivedAt, task.DeletedAt, task.RuleDate, op.TaskID, userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены операции %s: %v", op.Kind, err)
+	}
+	// Возврат задачи в прежнее состояние — такое же изменение полей, как PUT
+	if err := recordChanges(tx, userID, taskChanges(current, task, time.Now())); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordChanges записывает изменения полей задачи в журнал изменений
+func recordChanges(tx *sql.Tx, userID int64, changes []TaskChange) error {
+	for _, change := range changes {
+		_, err := tx.Exec(`
+			INSERT INTO task_changes (task_id, user_id, field, old_value, new_value, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?);
+		`, change.TaskID, userID, change.Field, change.Old, change.New, change.ChangedAt)
+		if err != nil {
+			return fmt.Errorf("ошибка записи в журнал изменений: %v", err)
+		}
+	}
+	return nil
+}
+
+// checkAffected возвращает ErrTaskNotFound, если запрос не затронул ни одной строки
+func checkAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка проверки обновления: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	// База часовых поясов встраивается в бинарный файл, чтобы tz работал и без zoneinfo в системе
+	_ "time/tzdata"
+)
+
+// taskLocation возвращает часовой пояс задачи: зону IANA из поля tz
+// или часовой пояс сервера, если поле не заполнено
+func taskLocation(task Task) (*time.Location, error) {
+	if task.TZ == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(task.TZ)
+	if err != nil || task.TZ == "Local" {
+		return nil, fmt.Errorf("неизвестный часовой пояс, ожидается название зоны IANA, например Europe/Moscow: %s", task.TZ)
+	}
+	return loc, nil
+}
+
+// todayIn возвращает сегодняшнюю дату в часовом поясе loc в том же виде, что и today
+func todayIn(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// taskToday возвращает сегодняшнюю дату в часовом поясе задачи
+func taskToday(task Task) (time.Time, error) {
+	loc, err := taskLocation(task)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return todayIn(loc), nil
+}
+
+// checkDueTime проверяет время выполнения задачи HH:MM и её часовой пояс.
+// Задаче с правилом h или n время обязательно
+func checkDueTime(task Task) error {
+	if task.Time == "" && isIntradayRule(task.Repeat) {
+		return fmt.Errorf("для правил h и n нужно указать время выполнения 'time'")
+	}
+	if task.Time != "" {
+		if _, err := time.Parse("15:04", task.Time); err != nil || len(task.Time) != 5 {
+			return fmt.Errorf("неправильный формат времени, ожидается HH:MM: %s", task.Time)
+		}
+	}
+	_, err := taskLocation(task)
+	return err
+}
+
+// localTime возвращает момент, когда в часовом поясе loc наступает время clock дня date.
+// Как в RFC 5545, время, пропущенное при переводе часов вперёд, сдвигается на величину
+// перевода (02:30 → 03:30), а время, повторяющееся при переводе назад, берётся в первый раз
+func localTime(date, clock time.Time, loc *time.Location) time.Time {
+	wall := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	// Смещения зоны за сутки до и через сутки после — по разные стороны возможного перевода часов
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	early := wall.Add(-time.Duration(before) * time.Second)
+	late := wall.Add(-time.Duration(after) * time.Second)
+	if late.Before(early) {
+		early, late = late, early
+	}
+	for _, t := range []time.Time{early, late} {
+		local := t.In(loc)
+		if local.Hour() == clock.Hour() && local.Minute() == clock.Minute() {
+			return local
+		}
+	}
+	// Время попало в пропуск при переводе часов: считаем его по смещению до перевода
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// withDue добавляет к задаче со временем выполнения момент выполнения в формате RFC 3339
+func withDue(task Task) Task {
+	if task.Time == "" {
+		return task
+	}
+	date, err := time.Parse("20060102", task.Date)
+	if err != nil {
+		return task
+	}
+	clock, err := time.Parse("15:04", task.Time)
+	if err != nil {
+		return task
+	}
+	loc, err := taskLocation(task)
+	if err != nil {
+		return task
+	}
+	task.Due = localTime(date, clock, loc).Format(time.RFC3339)
+	return task
+}
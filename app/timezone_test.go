@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	tbl := []struct {
+		date  string
+		clock string
+		want  string
+	}{
+		{"20250115", "09:00", "2025-01-15T09:00:00-05:00"},
+		{"20250715", "09:00", "2025-07-15T09:00:00-04:00"},
+		// 9 марта часы переводятся с 02:00 на 03:00: пропущенное время сдвигается вперёд
+		{"20250309", "02:30", "2025-03-09T03:30:00-04:00"},
+		{"20250309", "01:59", "2025-03-09T01:59:00-05:00"},
+		// 2 ноября время с 01:00 до 02:00 повторяется: берётся первое
+		{"20251102", "01:30", "2025-11-02T01:30:00-04:00"},
+		{"20251102", "02:00", "2025-11-02T02:00:00-05:00"},
+	}
+	for _, v := range tbl {
+		date, err := time.Parse("20060102", v.date)
+		assert.NoError(t, err)
+		clock, err := time.Parse("15:04", v.clock)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, localTime(date, clock, loc).Format(time.RFC3339), v.date+" "+v.clock)
+	}
+}
+
+func TestCheckDueTime(t *testing.T) {
+	assert.NoError(t, checkDueTime(Task{}))
+	assert.NoError(t, checkDueTime(Task{Time: "23:59", TZ: "Europe/Moscow"}))
+	for _, task := range []Task{{Time: "24:00"}, {Time: "9:00"}, {Time: "09:00:00"}, {TZ: "Mars/Olympus"}, {TZ: "Local"}} {
+		assert.Error(t, checkDueTime(task), task)
+	}
+}
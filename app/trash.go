@@ -0,0 +1,66 @@
+package app
+
+import "net/http"
+
+// handleTrash показывает корзину (GET), начиная с последних удалённых задач,
+// или удаляет задачу id из корзины насовсем (DELETE)
+func handleTrash(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := currentUser(req)
+
+		switch req.Method {
+		case http.MethodGet:
+			tasks, err := store.Trash(userID)
+			if err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			if tasks == nil {
+				tasks = []Task{}
+			}
+			for i := range tasks {
+				tasks[i] = withDue(withRepeatText(req, tasks[i]))
+			}
+			writeJSON(res, http.StatusOK, map[string]any{
+				"tasks": tasks,
+			})
+
+		case http.MethodDelete:
+			id := req.URL.Query().Get("id")
+			if id == "" {
+				writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+				return
+			}
+			if err := store.Purge(userID, id); err != nil {
+				writeStoreError(res, err)
+				return
+			}
+			writeJSON(res, http.StatusOK, map[string]any{})
+
+		default:
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		}
+	}
+}
+
+// handleTrashRestore возвращает задачу id из корзины в том состоянии, в котором её удалили
+func handleTrashRestore(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		id := req.URL.Query().Get("id")
+		if id == "" {
+			writeError(res, http.StatusBadRequest, "Не указан идентификатор задачи")
+			return
+		}
+
+		if err := store.Restore(currentUser(req), id); err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		writeJSON(res, http.StatusOK, map[string]any{})
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Виды операций в журнале для отмены
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	opDone   = "done"
+)
+
+// maxOperations — сколько последних операций пользователя хранится в журнале и сколько
+// можно отменить за один запрос
+const maxOperations = 100
+
+// Operation — запись журнала операций: что сделано с задачей и её состояние до этого.
+// Отмена операции create удаляет задачу насовсем, остальных — возвращает из Before поля,
+// изменённые операцией (см. undoneTask), а для done ещё и удаляет запись журнала выполнения
+type Operation struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	TaskID    string `json:"task_id"`
+	Title     string `json:"title"`
+	CreatedAt string `json:"created_at"` // момент операции в UTC, RFC 3339
+	// Skipped — задача удалена насовсем, и отмена не смогла её вернуть. Задаётся только в ответе Undo
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Before — задача до операции (для create пустая), CompletionID — запись журнала
+	// выполнения, добавленная операцией done. Нужны только для отмены
+	Before       Task   `json:"-"`
+	CompletionID string `json:"-"`
+}
+
+// newOperation возвращает запись журнала об операции kind над задачей taskID
+// с заголовком title, которая была в состоянии before
+func newOperation(kind, taskID, title string, before Task) Operation {
+	return Operation{
+		Kind:      kind,
+		TaskID:    taskID,
+		Title:     title,
+		CreatedAt: timestamp(time.Now()),
+		Before:    before,
+	}
+}
+
+// undoneTask возвращает задачу current после отмены операции op. Возвращаются только поля,
+// которые меняет сама операция: архив и восстановление из корзины в журнал не записываются,
+// поэтому отмена изменения не трогает состояние задачи и отметку корзины
+func undoneTask(op Operation, current Task) Task {
+	before := op.Before
+	switch op.Kind {
+	case opDelete:
+		current.DeletedAt = before.DeletedAt
+		return current
+	case opDone:
+		// Выполнение одноразовой задачи или последнего повторения переводит задачу в done
+		if current.Status == statusDone {
+			current.Status, current.ArchivedAt = before.Status, before.ArchivedAt
+		}
+	}
+	before.ID = current.ID
+	before.Status, before.ArchivedAt, before.DeletedAt = current.Status, current.ArchivedAt, current.DeletedAt
+	return before
+}
+
+// handleUndo отменяет n последних операций пользователя (по умолчанию одну), начиная
+// с последней, и возвращает отменённые операции. Операции над задачами, удалёнными
+// насовсем, возвращаются с признаком skipped
+func handleUndo(store TaskStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(res, http.StatusMethodNotAllowed, "Метод не поддерживается")
+			return
+		}
+
+		n := 1
+		if param := req.URL.Query().Get("n"); param != "" {
+			var err error
+			n, err = strconv.Atoi(param)
+			if err != nil || n < 1 || n > maxOperations {
+				writeError(res, http.StatusBadRequest, fmt.Sprintf("Параметр n должен быть числом от 1 до %d", maxOperations))
+				return
+			}
+		}
+
+		undone, err := store.Undo(currentUser(req), n)
+		if err != nil {
+			writeStoreError(res, err)
+			return
+		}
+		if undone == nil {
+			undone = []Operation{}
+		}
+		writeJSON(res, http.StatusOK, map[string]any{
+			"undone": undone,
+		})
+	}
+}
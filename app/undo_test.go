@@ -0,0 +1,212 @@
+package app
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndo(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		now := time.Now()
+		created, err := store.Create(1, Task{Date: "20250106", Title: "Зарядка", Repeat: "d 1", Count: "3"})
+		assert.NoError(t, err, name)
+		id := strconv.FormatInt(created, 10)
+
+		task, err := store.Get(1, id)
+		assert.NoError(t, err, name)
+		task.Title, task.Comment = "Утренняя зарядка", "10 минут"
+		assert.NoError(t, store.Update(1, task), name)
+		moved := task
+		moved.Date, moved.Count = "20250107", "2"
+		assert.NoError(t, store.Complete(1, moved, newCompletion(task, now)), name)
+		assert.NoError(t, store.Delete(1, id, now), name)
+		assert.ErrorIs(t, store.Update(2, task), ErrTaskNotFound, name)
+
+		// Чужие операции отменить нельзя
+		ops, err := store.Undo(2, 10)
+		assert.NoError(t, err, name)
+		assert.Empty(t, ops, name)
+
+		// Отмена удаления возвращает задачу из корзины
+		ops, err = store.Undo(1, 1)
+		assert.NoError(t, err, name)
+		if assert.Len(t, ops, 1, name) {
+			assert.Equal(t, opDelete, ops[0].Kind, name)
+			assert.Equal(t, id, ops[0].TaskID, name)
+		}
+		task, err = store.Get(1, id)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "20250107", task.Date, name)
+
+		// Отмена выполнения возвращает прежнюю дату и счётчик и удаляет запись журнала выполнения
+		ops, err = store.Undo(1, 2)
+		assert.NoError(t, err, name)
+		var kinds []string
+		for _, op := range ops {
+			kinds = append(kinds, op.Kind)
+		}
+		assert.Equal(t, []string{opDone, opUpdate}, kinds, name)
+		task, err = store.Get(1, id)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "20250106", task.Date, name)
+		assert.Equal(t, "3", task.Count, name)
+		assert.Equal(t, "Зарядка", task.Title, name)
+		assert.Empty(t, task.Comment, name)
+		history, err := store.History(1, CompletionFilter{TaskID: id})
+		assert.NoError(t, err, name)
+		assert.Empty(t, history, name)
+
+		// Отмена создания удаляет задачу насовсем
+		ops, err = store.Undo(1, maxOperations)
+		assert.NoError(t, err, name)
+		if assert.Len(t, ops, 1, name) {
+			assert.Equal(t, opCreate, ops[0].Kind, name)
+			assert.Equal(t, "Зарядка", ops[0].Title, name)
+		}
+		_, err = store.Get(1, id)
+		assert.ErrorIs(t, err, ErrTaskNotFound, name)
+		trash, err := store.Trash(1)
+		assert.NoError(t, err, name)
+		assert.Empty(t, trash, name)
+		ops, err = store.Undo(1, 1)
+		assert.NoError(t, err, name)
+		assert.Empty(t, ops, name)
+
+		// В журнале остаются только последние maxOperations операций
+		for i := 0; i <= maxOperations; i++ {
+			_, err := store.Create(3, Task{Date: "20250106", Title: strconv.Itoa(i)})
+			assert.NoError(t, err, name)
+		}
+		ops, err = store.Undo(3, maxOperations)
+		assert.NoError(t, err, name)
+		assert.Len(t, ops, maxOperations, name)
+		tasks, err := store.List(3, TaskFilter{})
+		assert.NoError(t, err, name)
+		if assert.Len(t, tasks, 1, name) {
+			assert.Equal(t, "0", tasks[0].Title, name)
+		}
+	}
+}
+
+func TestUndoPurged(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		now := time.Now()
+		created, err := store.Create(1, Task{Date: "20250106", Title: "Полить цветы", Repeat: "d 3"})
+		assert.NoError(t, err, name)
+		id := strconv.FormatInt(created, 10)
+		task, err := store.Get(1, id)
+		assert.NoError(t, err, name)
+		moved := task
+		moved.Date = "20250109"
+		assert.NoError(t, store.Complete(1, moved, newCompletion(task, now)), name)
+		assert.NoError(t, store.Delete(1, id, now), name)
+		assert.NoError(t, store.Purge(1, id), name)
+
+		// Операции над задачей, удалённой насовсем, отмечаются пропущенными,
+		// а запись журнала выполнения удаляется
+		ops, err := store.Undo(1, maxOperations)
+		assert.NoError(t, err, name)
+		var kinds []string
+		for _, op := range ops {
+			kinds = append(kinds, op.Kind)
+			assert.True(t, op.Skipped, name)
+		}
+		assert.Equal(t, []string{opDelete, opDone, opCreate}, kinds, name)
+		history, err := store.History(1, CompletionFilter{})
+		assert.NoError(t, err, name)
+		assert.Empty(t, history, name)
+		_, err = store.Get(1, id)
+		assert.ErrorIs(t, err, ErrTaskNotFound, name)
+	}
+}
+
+func TestMemoryUndoAtomic(t *testing.T) {
+	store := newMemoryStore()
+	store.record(1, newOperation(opUpdate, "не число", "Сломанная запись", Task{}))
+	created, err := store.Create(1, Task{Date: "20250106", Title: "Купить хлеб"})
+	assert.NoError(t, err)
+	id := strconv.FormatInt(created, 10)
+	task, err := store.Get(1, id)
+	assert.NoError(t, err)
+	task.Title = "Купить хлеб и молоко"
+	assert.NoError(t, store.Update(1, task))
+
+	// Ошибка в третьей операции отменяет отмену первых двух
+	_, err = store.Undo(1, 3)
+	assert.Error(t, err)
+	task, err = store.Get(1, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Купить хлеб и молоко", task.Title)
+
+	ops, err := store.Undo(1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, ops, 2)
+	_, err = store.Get(1, id)
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+func TestUndoKeepsArchive(t *testing.T) {
+	db, err := openDb(Config{DbFilePath: filepath.Join(t.TempDir(), "scheduler.db")})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for name, store := range map[string]TaskStore{"memory": newMemoryStore(), "sqlite": newSQLiteStore(db)} {
+		now := time.Now()
+		created, err := store.Create(1, Task{Date: "20250106", Title: "Оплатить интернет", Repeat: "d 30"})
+		assert.NoError(t, err, name)
+		id := strconv.FormatInt(created, 10)
+		task, err := store.Get(1, id)
+		assert.NoError(t, err, name)
+		task.Title = "Оплатить интернет и ТВ"
+		assert.NoError(t, store.Update(1, task), name)
+		moved := task
+		moved.Date = "20250205"
+		assert.NoError(t, store.Complete(1, moved, newCompletion(task, now)), name)
+		// Архив в журнал операций не записывается
+		assert.NoError(t, store.Archive(1, id, now), name)
+
+		// Отмена выполнения и изменения возвращает дату и заголовок, но задача остаётся в архиве
+		ops, err := store.Undo(1, 2)
+		assert.NoError(t, err, name)
+		assert.Len(t, ops, 2, name)
+		_, err = store.Get(1, id)
+		assert.ErrorIs(t, err, ErrTaskNotFound, name)
+		archived, err := store.List(1, TaskFilter{Status: statusArchived})
+		assert.NoError(t, err, name)
+		if assert.Len(t, archived, 1, name) {
+			assert.Equal(t, "20250106", archived[0].Date, name)
+			assert.Equal(t, "Оплатить интернет", archived[0].Title, name)
+			assert.NotEmpty(t, archived[0].ArchivedAt, name)
+		}
+
+		// Отмена удаления задачи, уже восстановленной из корзины, ничего не меняет
+		created, err = store.Create(1, Task{Date: "20250106", Title: "Разобрать почту"})
+		assert.NoError(t, err, name)
+		id = strconv.FormatInt(created, 10)
+		assert.NoError(t, store.Delete(1, id, now), name)
+		assert.NoError(t, store.Restore(1, id), name)
+		task, err = store.Get(1, id)
+		assert.NoError(t, err, name)
+		task.Title = "Разобрать почту до обеда"
+		assert.NoError(t, store.Update(1, task), name)
+		ops, err = store.Undo(1, 2)
+		assert.NoError(t, err, name)
+		assert.Len(t, ops, 2, name)
+		task, err = store.Get(1, id)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "Разобрать почту", task.Title, name)
+		assert.Empty(t, task.DeletedAt, name)
+	}
+}
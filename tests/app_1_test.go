@@ -14,6 +14,10 @@ import (
 )
 
 func getURL(path string) string {
+	path = strings.TrimPrefix(strings.ReplaceAll(path, `\`, `/`), `../web/`)
+	if len(serverURL) > 0 {
+		return serverURL + "/" + path
+	}
 	port := Port
 	envPort := os.Getenv("TODO_PORT")
 	if len(envPort) > 0 {
@@ -21,7 +25,6 @@ func getURL(path string) string {
 			port = int(eport)
 		}
 	}
-	path = strings.TrimPrefix(strings.ReplaceAll(path, `\`, `/`), `../web/`)
 	return fmt.Sprintf("http://localhost:%d/%s", port, path)
 }
 
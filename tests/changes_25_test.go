@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taskChange struct {
+	TaskID    string `json:"task_id"`
+	Field     string `json:"field"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+	UserID    string `json:"user_id"`
+	ChangedAt string `json:"changed_at"`
+}
+
+func getChanges(t *testing.T, query string) []taskChange {
+	body, err := requestJSON("api/task/changes?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Changes []taskChange `json:"changes"`
+		Error   string       `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Empty(t, m.Error)
+	return m.Changes
+}
+
+func TestTaskChanges(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	id := addTask(t, task{date: day(1), title: "Сдать отчёт"})
+	for _, update := range []map[string]any{
+		{"id": id, "date": day(3), "title": "Сдать отчёт"},
+		{"id": id, "date": day(3), "title": "Сдать квартальный отчёт", "comment": "До обеда"},
+	} {
+		ret, err := postJSON("api/task", update, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	changes := getChanges(t, "id="+id)
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+		assert.Equal(t, id, change.TaskID)
+		assert.NotEmpty(t, change.UserID)
+		changedAt, err := time.Parse(time.RFC3339, change.ChangedAt)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, now, changedAt, time.Minute)
+	}
+	assert.Equal(t, []string{"comment", "title", "date"}, fields)
+
+	// Кто и когда перенёс срок
+	changes = getChanges(t, "id="+id+"&field=date")
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, day(1), changes[0].Old)
+		assert.Equal(t, day(3), changes[0].New)
+	}
+	assert.Len(t, getChanges(t, "id="+id+"&limit=1"), 1)
+
+	for _, query := range []string{"field=status", "limit=0", "limit=abc"} {
+		body, err := requestJSON("api/task/changes?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "error", query)
+	}
+
+	// Отмена последнего изменения записывается в журнал как обратное изменение
+	ret, err := postJSON("api/undo?n=1", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	changes = getChanges(t, "id="+id+"&limit=2")
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, taskChange{TaskID: id, Field: "comment", Old: "До обеда", New: "",
+			UserID: changes[0].UserID, ChangedAt: changes[0].ChangedAt}, changes[0])
+		assert.Equal(t, "title", changes[1].Field)
+		assert.Equal(t, "Сдать отчёт", changes[1].New)
+	}
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	// Журнал изменений остаётся и после удаления задачи
+	assert.Len(t, getChanges(t, "id="+id), 5)
+}
@@ -11,11 +11,23 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID          int64  `db:"id"`
+	Date        string `db:"date"`
+	Title       string `db:"title"`
+	Comment     string `db:"comment"`
+	Repeat      string `db:"repeat"`
+	UserID      int64  `db:"user_id"`
+	RepeatCount int64  `db:"repeat_count"`
+	RepeatUntil string `db:"repeat_until"`
+	Exdates     string `db:"exdates"`
+	Shift       string `db:"shift"`
+	DueTime     string `db:"due_time"`
+	TZ          string `db:"tz"`
+	RepeatMode  string `db:"repeat_mode"`
+	Status      string `db:"status"`
+	ArchivedAt  string `db:"archived_at"`
+	DeletedAt   string `db:"deleted_at"`
+	RuleDate    string `db:"rule_date"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -26,7 +38,7 @@ func count(db *sqlx.DB) (int, error) {
 func openDB(t *testing.T) *sqlx.DB {
 	dbfile := DBFile
 	envFile := os.Getenv("TODO_DBFILE")
-	if len(envFile) > 0 {
+	if len(envFile) > 0 && len(serverURL) == 0 {
 		dbfile = envFile
 	}
 	db, err := sqlx.Connect("sqlite3", dbfile)
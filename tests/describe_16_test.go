@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatText(t *testing.T) {
+	id := addTask(t, task{
+		date:   time.Now().Format(`20060102`),
+		title:  "Отчёт по проекту",
+		repeat: "m 10,17 12,8,1",
+	})
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "10 и 17 января, августа и декабря", m["repeat_text"])
+
+	body, err = requestJSON("api/task?lang=en&id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "on the 10th and 17th of January, August and December", m["repeat_text"])
+
+	found := false
+	for _, task := range getTasks(t, "Отчёт") {
+		if task["id"] == id {
+			found = true
+			assert.Equal(t, "10 и 17 января, августа и декабря", task["repeat_text"])
+		}
+	}
+	assert.True(t, found)
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExdates(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":    day(0),
+		"title":   "Неверное исключение",
+		"repeat":  "d 1",
+		"exdates": "2024-01-01",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	// Дата задачи сама попадает на исключение — задача переносится на следующее повторение
+	ret, err := postJSON("api/task", map[string]any{
+		"date":    day(0),
+		"title":   "Планёрка",
+		"repeat":  "d 1",
+		"exdates": day(2) + "," + day(0) + "," + day(3) + "," + day(2),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, day(1), stored.Date)
+	assert.Equal(t, day(0)+","+day(2)+","+day(3), stored.Exdates)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, day(4), stored.Date)
+
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, day(5), ret["date"])
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, day(5), stored.Date)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Пропуск одноразовой задачи не имеет смысла
+	id = addTask(t, task{
+		date:  day(0),
+		title: "Одноразовая",
+	})
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Пропуск не расходует повторения из count: задача с одним оставшимся повторением
+	// переносится на следующую дату, а после выполнения завершается
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   day(0),
+		"title":  "Последний раз",
+		"repeat": "d 7",
+		"count":  "1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, day(7), ret["date"])
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, day(7), stored.Date)
+	assert.Equal(t, int64(1), stored.RepeatCount)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	// Пропуск последнего повторения до until завершает задачу
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   day(0),
+		"title":  "До конца недели",
+		"repeat": "d 7",
+		"until":  day(3),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	notFoundTask(t, id)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`
+	Date        string `json:"date"`
+	CompletedAt string `json:"completed_at"`
+}
+
+func getHistory(t *testing.T, query string) []completion {
+	body, err := requestJSON("api/history?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Completions []completion `json:"completions"`
+		Error       string       `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Empty(t, m.Error)
+	return m.Completions
+}
+
+func TestHistory(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   day(0),
+		"title":  "Зарядка",
+		"repeat": "d 1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	repeating := fmt.Sprint(ret["id"])
+	oneOff := addTask(t, task{date: day(0), title: "Оплатить интернет"})
+
+	for _, id := range []string{repeating, repeating, oneOff} {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	// Пропуск повторения выполнением не считается
+	ret, err = postJSON("api/task/skip?id="+repeating, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	history := getHistory(t, "id="+repeating)
+	if assert.Len(t, history, 2) {
+		// Последние выполнения — первыми
+		assert.Equal(t, day(1), history[0].Date)
+		assert.Equal(t, day(0), history[1].Date)
+		assert.Equal(t, "Зарядка", history[0].Title)
+		completedAt, err := time.Parse(time.RFC3339, history[0].CompletedAt)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, now, completedAt, time.Minute)
+	}
+
+	// Одноразовая задача удалена, но её выполнение осталось в журнале
+	notFoundTask(t, oneOff)
+	history = getHistory(t, "id="+oneOff)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, "Оплатить интернет", history[0].Title)
+	}
+
+	var count int
+	err = db.Get(&count, `SELECT count(*) FROM completions WHERE task_id IN (?, ?)`, repeating, oneOff)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.Len(t, getHistory(t, "limit=1"), 1)
+	assert.Empty(t, getHistory(t, "id="+repeating+"&from="+day(1)))
+	assert.Len(t, getHistory(t, "id="+repeating+"&from="+day(0)+"&to="+day(0)), 2)
+
+	body, err := requestJSON("api/history?limit=0", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	_, err = postJSON("api/task?id="+repeating, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntradayRepeat(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	day := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	for _, values := range []map[string]any{
+		{"title": "Без времени", "date": day(1), "repeat": "h 2"},
+		{"title": "С переносом", "date": day(1), "repeat": "h 2", "time": "09:00", "shift": "next"},
+		{"title": "Неверное окно", "date": day(1), "repeat": "h 2 18:00-09:00", "time": "09:00"},
+	} {
+		m, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], values)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   day(10),
+		"time":   "16:00",
+		"title":  "Проверка дежурного",
+		"repeat": "h 2 09:00-18:00",
+		"tz":     "UTC",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	var stored Task
+	for _, want := range []struct{ date, time string }{
+		{day(10), "18:00"},
+		{day(11), "09:00"},
+		{day(11), "11:00"},
+	} {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, want.date, stored.Date)
+		assert.Equal(t, want.time, stored.DueTime)
+	}
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// 6 января 2025 года — понедельник, следующий разрешённый день — пятница
+	m := getOccurrences(t, "date=20250106&time=16:00&tz=UTC&limit=4&repeat="+url.QueryEscape("h 2 09:00-18:00 1,5"))
+	assert.Equal(t, []any{"20250106", "20250106", "20250110", "20250110"}, m["dates"])
+	assert.Equal(t, []any{"16:00", "18:00", "09:00", "11:00"}, m["times"])
+
+	// При переводе часов назад повторяющийся час проходится дважды, и список идёт дальше
+	m = getOccurrences(t, "date=20261025&time=01:30&tz=Europe/Berlin&limit=4&repeat="+url.QueryEscape("h 1"))
+	assert.Equal(t, []any{"01:30", "02:30", "02:30", "03:30"}, m["times"])
+	m = getOccurrences(t, "date=20261025&time=02:00&tz=Europe/Berlin&limit=5&repeat="+url.QueryEscape("n 30"))
+	assert.Equal(t, []any{"02:00", "02:30", "02:00", "02:30", "03:00"}, m["times"])
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatLimits(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	date := now.Format(`20060102`)
+
+	for _, v := range []map[string]any{
+		{"date": date, "title": "Без правила", "count": "3"},
+		{"date": date, "title": "Ноль повторений", "repeat": "d 1", "count": "0"},
+		{"date": date, "title": "Не число", "repeat": "d 1", "count": "три"},
+		{"date": date, "title": "Плохая дата", "repeat": "d 1", "until": "2024-01-01"},
+		{"date": date, "title": "Уже закончилась", "repeat": "d 1", "until": "20240101"},
+	} {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для задачи %v", v)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   date,
+		"title":  "Три раза через неделю",
+		"repeat": "d 7",
+		"count":  "3",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	for i := 1; i < 3; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, 7*i).Format(`20060102`), task.Date)
+		assert.Equal(t, int64(3-i), task.RepeatCount)
+	}
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   date,
+		"title":  "До конца следующей недели",
+		"repeat": "d 5",
+		"until":  now.AddDate(0, 0, 12).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"until":"`+now.AddDate(0, 0, 12).Format(`20060102`)+`"`)
+
+	for i := 1; i < 3; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 10).Format(`20060102`), task.Date)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestRepeatCountNumber(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().Format(`20060102`)
+
+	for _, count := range []any{0, -1, 2.5, true} {
+		m, err := postJSON("api/task", map[string]any{
+			"date": date, "title": "Неправильное число", "repeat": "d 1", "count": count,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для count %v", count)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   date,
+		"title":  "Число повторений числом",
+		"repeat": "d 1",
+		"count":  3,
+		"until":  time.Now().AddDate(0, 0, 10).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+
+	task := getTaskFields(t, id)
+	assert.Equal(t, "3", task["count"])
+
+	// PUT заменяет задачу целиком: поля count и until, которых нет в запросе, очищаются
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   date,
+		"title":  "Без ограничений",
+		"repeat": "d 1",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stored.RepeatCount)
+	assert.Empty(t, stored.RepeatUntil)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   date,
+		"title":  "Снова два раза",
+		"repeat": "d 1",
+		"count":  2,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, "2", getTaskFields(t, id)["count"])
+}
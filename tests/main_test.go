@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/app"
+)
+
+// serverURL — адрес сервера, запущенного внутри тестов. Пустой, если тесты
+// обращаются к внешнему серверу через TODO_PORT
+var serverURL string
+
+// TestMain запускает сервер в процессе тестов с временной базой данных.
+// Если задана переменная TODO_PORT, тесты работают с уже запущенным сервером
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	if len(os.Getenv("TODO_PORT")) > 0 {
+		return m.Run()
+	}
+
+	dir, err := os.MkdirTemp("", "scheduler-test")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	DBFile = filepath.Join(dir, "scheduler.db")
+	handler, closeDb, err := app.NewServer(app.Config{
+		DbFilePath: DBFile,
+		WebDir:     "../web",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer closeDb()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	serverURL = server.URL
+
+	return m.Run()
+}
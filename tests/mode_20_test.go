@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatMode(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	day := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	for _, values := range []map[string]any{
+		{"title": "Неверный режим", "date": day(1), "repeat": "d 3", "mode": "sometimes"},
+		{"title": "Режим без правила", "date": day(1), "mode": "completion"},
+	} {
+		m, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], values)
+	}
+
+	// По расписанию следующая дата считается от даты задачи, в режиме completion — от дня выполнения
+	for _, v := range []struct {
+		mode string
+		want string
+	}{
+		{"", day(8)},
+		{"schedule", day(8)},
+		{"completion", day(3)},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":   day(5),
+			"title":  "Полить цветы",
+			"repeat": "d 3",
+			"mode":   v.mode,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var stored Task
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, stored.Date, v.mode)
+		assert.Equal(t, v.mode, stored.RepeatMode)
+
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+
+	// Для правил h и n в режиме completion интервал отсчитывается от времени выполнения
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   day(5),
+		"time":   "09:00",
+		"title":  "Принять лекарство",
+		"repeat": "h 8",
+		"tz":     "UTC",
+		"mode":   "completion",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	next, err := time.Parse("20060102 15:04", stored.Date+" "+stored.DueTime)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(8*time.Hour), next, 2*time.Minute)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNaturalRepeat(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   now,
+		"title":  "Йога",
+		"repeat": "каждый понедельник и пятницу",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "w 1,5", ret["repeat"])
+	assert.Equal(t, "по понедельникам и пятницам", ret["repeat_text"])
+	id := fmt.Sprint(ret["id"])
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "w 1,5", task.Repeat)
+
+	ret, err = postJSON("api/task?lang=en", map[string]any{
+		"id":     id,
+		"date":   now,
+		"title":  "Йога",
+		"repeat": "last day of month",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "m -1", ret["repeat"])
+	assert.Equal(t, "on the last day of every month", ret["repeat_text"])
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   now,
+		"title":  "Йога",
+		"repeat": "w 1,5",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   now,
+		"title":  "Йога",
+		"repeat": "когда будет настроение",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -77,3 +77,29 @@ func TestNextDate(t *testing.T) {
 	}
 	check()
 }
+
+func TestNextDateFarPast(t *testing.T) {
+	// Правило d переносится сразу на ближайшее повторение, остальные правила
+	// не перебирают больше ограниченного числа дат
+	for _, v := range []nextDate{
+		{"16240126", "d 1", "20240127"},
+		{"16240126", "d 7", "20240202"},
+		{"00010101", "d 1", ""},
+		{"00010101", "b 1", ""},
+		{"00010101", "RRULE:FREQ=DAILY;COUNT=1000000", ""},
+	} {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		start := time.Now()
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second, v.date+" "+v.repeat)
+		next := strings.TrimSpace(string(get))
+		if v.want == "" {
+			_, err = time.Parse("20060102", next)
+			assert.Error(t, err, `{%q, %q}`, v.date, v.repeat)
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q}`, v.date, v.repeat)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getOccurrences(t *testing.T, query string) map[string]any {
+	body, err := requestJSON("api/occurrences?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		query string
+		want  []any
+	}{
+		{"date=20240101&repeat=" + url.QueryEscape("d 10") + "&limit=4",
+			[]any{"20240101", "20240111", "20240121", "20240131"}},
+		{"date=20240101&repeat=" + url.QueryEscape("w 2#2") + "&limit=3",
+			[]any{"20240109", "20240213", "20240312"}},
+		{"date=20240131&repeat=" + url.QueryEscape("m -1") + "&from=20240301&to=20240630",
+			[]any{"20240331", "20240430", "20240531", "20240630"}},
+		{"date=20240101&repeat=" + url.QueryEscape("RRULE:FREQ=WEEKLY;COUNT=3"),
+			[]any{"20240101", "20240108", "20240115"}},
+		{"date=20240101&repeat=" + url.QueryEscape("m 3") + "&shift=next&limit=3",
+			[]any{"20240103", "20240205", "20240304"}},
+		{"date=20240101&repeat=" + url.QueryEscape("m 6") + "&shift=next&limit=3",
+			[]any{"20240108", "20240206", "20240306"}},
+		{"date=20240101&repeat=" + url.QueryEscape("RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=2"),
+			[]any{"20240126", "20240223"}},
+		{"date=20240101&repeat=y&from=20240102&to=20241231", []any{}},
+		{"date=20240101", []any{"20240101"}},
+	}
+	for _, v := range tbl {
+		m := getOccurrences(t, v.query)
+		assert.Empty(t, m["error"], v.query)
+		assert.Equal(t, v.want, m["dates"], v.query)
+	}
+
+	m := getOccurrences(t, "date=20240101&repeat="+url.QueryEscape("d 1")+"&to=21240101")
+	assert.Len(t, m["dates"], 500)
+
+	for _, query := range []string{
+		"date=20240101&repeat=" + url.QueryEscape("d 1") + "&limit=501",
+		"date=20240101&repeat=" + url.QueryEscape("d 1") + "&limit=0",
+		"date=20240101&repeat=" + url.QueryEscape("k 34"),
+		"date=20240101&repeat=" + url.QueryEscape("d 1") + "&to=2024-12-31",
+		"id=999999999",
+	} {
+		m := getOccurrences(t, query)
+		assert.NotEmpty(t, m["error"], query)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":    "20990101",
+		"title":   "Предпросмотр",
+		"repeat":  "d 1",
+		"count":   "3",
+		"exdates": "20990102",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	m = getOccurrences(t, "id="+id)
+	assert.Equal(t, []any{"20990101", "20990103", "20990104"}, m["dates"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
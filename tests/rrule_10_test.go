@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "RRULE:FREQ=DAILY;INTERVAL=10", "20240131"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", "20240131"},
+		{"20240101", "rrule:freq=yearly;bymonth=2;bymonthday=29", "20240229"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYDAY=1MO", "20250106"},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240127", "20240127"},
+		{"20240120", "RRULE:FREQ=DAILY;COUNT=10", "20240127"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240125", ""},
+		{"20240101", "RRULE:FREQ=HOURLY", ""},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=2MO", ""},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=31;BYMONTH=2", ""},
+		{"20240101", "RRULE:INTERVAL=2", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if _, err = time.Parse("20060102", next); err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`, v.date, v.repeat, v.want)
+	}
+}
+
+func TestRepeatConvert(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		native string
+		rrule  string
+	}{
+		{"d 5", "d 5", "RRULE:FREQ=DAILY;INTERVAL=5"},
+		{"y", "y", "RRULE:FREQ=YEARLY"},
+		{"w 1,7", "w 1,7", "RRULE:FREQ=WEEKLY;BYDAY=MO,SU"},
+		{"m -1,15 1,6", "m -1,15 1,6", "RRULE:FREQ=MONTHLY;BYMONTH=1,6;BYMONTHDAY=-1,15"},
+		{"RRULE:FREQ=DAILY", "d 1", "RRULE:FREQ=DAILY"},
+		{"RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU", "", "RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU"},
+		{"RRULE:FREQ=DAILY;COUNT=3", "", "RRULE:FREQ=DAILY;COUNT=3"},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/repeat/convert?repeat=" + url.QueryEscape(v.repeat))
+		assert.NoError(t, err)
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, v.native, m["repeat"], v.repeat)
+		assert.Equal(t, v.rrule, m["rrule"], v.repeat)
+	}
+
+	body, err := getBody("api/repeat/convert?repeat=" + url.QueryEscape("k 34"))
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["error"])
+}
+
+func TestDoneRRuleCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Серия из двух повторений",
+		repeat: "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=2",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), task.Date)
+	assert.Equal(t, "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=1", task.Repeat)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
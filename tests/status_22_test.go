@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTasksByStatus(t *testing.T, status string) map[string]map[string]string {
+	body, err := requestJSON("api/tasks?status="+status, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	byID := make(map[string]map[string]string)
+	for _, task := range m["tasks"] {
+		byID[task["id"]] = task
+	}
+	return byID
+}
+
+func TestTaskStatus(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	done := addTask(t, task{date: today, title: "Отправить отчёт"})
+	archived := addTask(t, task{date: today, title: "Устаревшая идея"})
+	active := addTask(t, task{date: today, title: "Текущая задача"})
+
+	ret, err := postJSON("api/task/done?id="+done, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/archive?id="+archived, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/archive?id="+archived, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Архивную задачу нельзя перенести в архив ещё раз")
+
+	// Выполненная задача не удаляется, а остаётся с состоянием done
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, done)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", stored.Status)
+	assert.Equal(t, today, stored.Date)
+	archivedAt, err := time.Parse(time.RFC3339, stored.ArchivedAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), archivedAt, time.Minute)
+	notFoundTask(t, done)
+	notFoundTask(t, archived)
+
+	tasks := getTasksByStatus(t, "")
+	assert.Contains(t, tasks, active)
+	assert.NotContains(t, tasks, done)
+	assert.NotContains(t, tasks, archived)
+
+	tasks = getTasksByStatus(t, "done")
+	assert.Contains(t, tasks, done)
+	assert.NotContains(t, tasks, active)
+	assert.Equal(t, "done", tasks[done]["status"])
+
+	tasks = getTasksByStatus(t, "archived")
+	assert.Contains(t, tasks, archived)
+	assert.Equal(t, "Устаревшая идея", tasks[archived]["title"])
+
+	tasks = getTasksByStatus(t, "all")
+	for _, id := range []string{done, archived, active} {
+		assert.Contains(t, tasks, id)
+	}
+
+	body, err := requestJSON("api/tasks?status=deleted", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	// Задачи из архива можно удалить насовсем
+	for _, id := range []string{done, archived, active} {
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+}
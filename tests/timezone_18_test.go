@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskTimeZone(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	for _, values := range []map[string]any{
+		{"title": "Неверное время", "time": "25:00"},
+		{"title": "Неверный пояс", "tz": "Europe/Atlantis"},
+	} {
+		m, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], values)
+	}
+
+	// Время, пропущенное при переходе на летнее время, сдвигается на час вперёд
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   "20250309",
+		"title":  "Созвон с Нью-Йорком",
+		"repeat": "y",
+		"time":   "02:30",
+		"tz":     "America/New_York",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "02:30", stored.DueTime)
+	assert.Equal(t, "America/New_York", stored.TZ)
+
+	// Прошедшая дата перенесена по правилу, возвращаем её, чтобы проверить момент выполнения
+	_, err = db.Exec(`UPDATE scheduler SET date = '20250309' WHERE id = ?`, id)
+	assert.NoError(t, err)
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "02:30", m["time"])
+	assert.Equal(t, "America/New_York", m["tz"])
+	assert.Equal(t, "2025-03-09T03:30:00-04:00", m["due"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Сегодняшний день берётся в часовом поясе задачи: в Киритимати (UTC+14)
+	// и Паго-Паго (UTC-11) всегда разные даты
+	for _, tz := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		loc, err := time.LoadLocation(tz)
+		assert.NoError(t, err)
+		today := time.Now().In(loc)
+
+		body, err := getBody("api/nextdate?date=20240101&repeat=d+1&tz=" + tz)
+		assert.NoError(t, err)
+		assert.Equal(t, today.AddDate(0, 0, 1).Format(`20060102`), string(body), tz)
+
+		ret, err := postJSON("api/task", map[string]any{
+			"title": "Задача без даты",
+			"tz":    tz,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, today.Format(`20060102`), stored.Date, tz)
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
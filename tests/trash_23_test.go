@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTrash(t *testing.T) map[string]map[string]string {
+	body, err := requestJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	byID := make(map[string]map[string]string)
+	for _, task := range m["tasks"] {
+		byID[task["id"]] = task
+	}
+	return byID
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	id := addTask(t, task{
+		date:    tomorrow,
+		title:   "Полить цветы",
+		comment: "Кактус реже",
+		repeat:  "d 3",
+	})
+
+	// Удалённая задача остаётся в базе и попадает в корзину
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	deletedAt, err := time.Parse(time.RFC3339, stored.DeletedAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), deletedAt, time.Minute)
+	assert.NotContains(t, getTasksByStatus(t, "all"), id)
+
+	trash := getTrash(t)
+	if assert.Contains(t, trash, id) {
+		assert.Equal(t, "Полить цветы", trash[id]["title"])
+		assert.Equal(t, "d 3", trash[id]["repeat"])
+		assert.Equal(t, stored.DeletedAt, trash[id]["deleted_at"])
+	}
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Задача уже в корзине")
+
+	// Восстановленная задача сохраняет правило повторения и комментарий
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var restored map[string]string
+	assert.NoError(t, json.Unmarshal(body, &restored))
+	assert.Equal(t, tomorrow, restored["date"])
+	assert.Equal(t, "Кактус реже", restored["comment"])
+	assert.Equal(t, "d 3", restored["repeat"])
+	assert.Empty(t, restored["deleted_at"])
+	assert.NotContains(t, getTrash(t), id)
+
+	for _, req := range []string{"api/trash/restore", "api/trash/restore?id=" + id} {
+		ret, err = postJSON(req, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], req)
+	}
+	// Удалить насовсем можно только задачу из корзины
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var count int
+	err = db.Get(&count, `SELECT count(id) FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NotContains(t, getTrash(t), id)
+}
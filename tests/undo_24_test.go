@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func undo(t *testing.T, n int) []string {
+	ret, err := postJSON(fmt.Sprintf("api/undo?n=%d", n), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	var kinds []string
+	undone, _ := ret["undone"].([]any)
+	for _, op := range undone {
+		kinds = append(kinds, fmt.Sprint(op.(map[string]any)["kind"]))
+	}
+	return kinds
+}
+
+func getTaskFields(t *testing.T, id string) map[string]string {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]string
+	assert.NoError(t, json.Unmarshal(body, &task))
+	return task
+}
+
+func TestUndo(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	id := addTask(t, task{
+		date:   day(0),
+		title:  "Полить цветы",
+		repeat: "d 3",
+	})
+	ret, err := postJSON("api/task", map[string]any{
+		"id":      id,
+		"date":    day(1),
+		"title":   "Полить цветы",
+		"comment": "Кактус реже",
+		"repeat":  "d 3",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, day(4), getTaskFields(t, id)["date"])
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	assert.Equal(t, []string{"delete"}, undo(t, 1))
+	assert.Equal(t, day(4), getTaskFields(t, id)["date"])
+
+	// Отмена выполнения возвращает прежнюю дату и убирает запись из журнала выполнения
+	assert.Equal(t, []string{"done"}, undo(t, 1))
+	assert.Equal(t, day(1), getTaskFields(t, id)["date"])
+	assert.Empty(t, getHistory(t, "id="+id))
+
+	assert.Equal(t, []string{"update"}, undo(t, 1))
+	task := getTaskFields(t, id)
+	assert.Equal(t, day(0), task["date"])
+	assert.Empty(t, task["comment"])
+
+	// Отмена создания удаляет задачу насовсем
+	assert.Equal(t, []string{"create"}, undo(t, 1))
+	notFoundTask(t, id)
+	var count int
+	err = db.Get(&count, `SELECT count(id) FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	for _, n := range []string{"0", "abc", "1000"} {
+		ret, err = postJSON("api/undo?n="+n, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], n)
+	}
+}
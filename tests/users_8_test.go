@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func userRequest(token, apipath string, values map[string]any, method string) (map[string]any, error) {
+	var data []byte
+	if len(values) > 0 {
+		var err error
+		data, err = json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	err = json.Unmarshal(body, &m)
+	return m, err
+}
+
+func signUp(t *testing.T, login string) string {
+	m, err := userRequest("", "api/signup", map[string]any{
+		"login":    login,
+		"password": "password-" + login,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	token, ok := m["token"].(string)
+	assert.True(t, ok && len(token) > 0, "Не возвращён токен для пользователя %s: %v", login, m)
+	return token
+}
+
+func TestUsers(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	alice := signUp(t, "alice"+suffix)
+	bob := signUp(t, "bob"+suffix)
+
+	m, err := userRequest("", "api/signup", map[string]any{
+		"login":    "alice" + suffix,
+		"password": "other",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка при повторной регистрации")
+
+	m, err = userRequest("", "api/signin", map[string]any{
+		"login":    "alice" + suffix,
+		"password": "wrong",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка при неверном пароле")
+
+	m, err = userRequest("", "api/signin", map[string]any{
+		"login":    "alice" + suffix,
+		"password": "password-alice" + suffix,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["token"])
+
+	m, err = userRequest(alice, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Задача Алисы",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+	assert.NotEmpty(t, id)
+
+	m, err = userRequest(alice, "api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "Задача Алисы", m["title"])
+
+	m, err = userRequest(bob, "api/tasks", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Empty(t, m["tasks"], "Пользователь видит чужие задачи")
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		m, err = userRequest(bob, "api/task?id="+id, nil, method)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для %s чужой задачи", method)
+	}
+	m, err = userRequest(bob, "api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Чужая задача",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка при изменении чужой задачи")
+	m, err = userRequest(bob, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка при выполнении чужой задачи")
+
+	m, err = userRequest("", "api/tasks", nil, http.MethodGet)
+	assert.NoError(t, err)
+	if len(Token) == 0 {
+		for _, v := range m["tasks"].([]any) {
+			assert.NotEqual(t, id, v.(map[string]any)["id"], "Задача пользователя попала в общий список")
+		}
+	}
+
+	m, err = userRequest(alice, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+}
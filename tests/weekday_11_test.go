@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateNthWeekday(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "w 2#2", "20240213"},
+		{"20240101", "w 5#-1", "20240223"},
+		{"20240126", "w 5#-1", "20240223"},
+		{"20240101", "w 1#1,5#-1", "20240205"},
+		{"20240101", "w 4#3 3,9", "20240321"},
+		{"20240101", "w 1#-2 12", "20241223"},
+		{"20240101", "w 5#5 2", "20360229"},
+		{"20240101", "w 6 2", "20240203"},
+		{"20240101", "w 1,2#1", "20240129"},
+		{"20240101", "w 2#6", ""},
+		{"20240101", "w 2#0", ""},
+		{"20240101", "w 8#1", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if _, err = time.Parse("20060102", next); err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`, v.date, v.repeat, v.want)
+	}
+}
+
+func TestRepeatConvertNthWeekday(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		native string
+		rrule  string
+	}{
+		{"w 2#2", "w 2#2", "RRULE:FREQ=MONTHLY;BYDAY=2TU"},
+		{"w 5#-1 1,7", "w 5#-1 1,7", "RRULE:FREQ=MONTHLY;BYMONTH=1,7;BYDAY=-1FR"},
+		{"w 1 6", "w 1 6", "RRULE:FREQ=WEEKLY;BYMONTH=6;BYDAY=MO"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-2SU", "w 7#-2", "RRULE:FREQ=MONTHLY;BYDAY=-2SU"},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/repeat/convert?repeat=" + url.QueryEscape(v.repeat))
+		assert.NoError(t, err)
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, v.native, m["repeat"], v.repeat)
+		assert.Equal(t, v.rrule, m["rrule"], v.repeat)
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateWorkdays(t *testing.T) {
+	tbl := []struct {
+		date   string
+		repeat string
+		shift  string
+		want   string
+	}{
+		// 26.01.2024 — пятница
+		{"20240126", "b 1", "", "20240129"},
+		{"20240126", "b 5", "", "20240202"},
+		{"20240122", "b 3", "", "20240130"},
+		{"20240126", "b 0", "", ""},
+		{"20240126", "b", "", ""},
+		{"20240101", "m 3", "", "20240203"},
+		{"20240101", "m 3", "next", "20240205"},
+		{"20240101", "m 3", "prev", "20240202"},
+		{"20240101", "m 27", "prev", "20240227"},
+		{"20240120", "w 6", "prev", "20240202"},
+		{"20240120", "w 6", "next", "20240129"},
+		{"20240101", "m 3", "later", ""},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s&shift=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat), v.shift)
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if _, err = time.Parse("20060102", next); err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q, %q}`, v.date, v.repeat, v.shift, v.want)
+	}
+}
+
+func TestTaskShift(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	m, err := postJSON("api/task", map[string]any{
+		"title": "Перенос без правила",
+		"shift": "next",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	// Задача на ближайшую субботу, повторяется каждые 7 дней с переносом на понедельник
+	now := time.Now()
+	saturday := now.AddDate(0, 0, (int(time.Saturday)-int(now.Weekday())+7)%7)
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   saturday.Format(`20060102`),
+		"title":  "Отчёт",
+		"repeat": "w 6",
+		"shift":  "next",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, saturday.AddDate(0, 0, 9).Format(`20060102`), task.Date)
+	assert.Equal(t, saturday.AddDate(0, 0, 7).Format(`20060102`), task.RuleDate)
+	assert.Equal(t, "next", task.Shift)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestShiftKeepsSchedule(t *testing.T) {
+	// Перенос с выходного не меняет расписание: следующие даты отсчитываются
+	// от даты по правилу, а не от перенесённой
+	tbl := []struct {
+		query string
+		want  []any
+	}{
+		{"date=20240101&repeat=" + url.QueryEscape("d 10") + "&shift=next&limit=5",
+			[]any{"20240101", "20240111", "20240122", "20240131", "20240212"}},
+		{"date=20240515&repeat=" + url.QueryEscape("RRULE:FREQ=MONTHLY") + "&shift=next&limit=6",
+			[]any{"20240515", "20240617", "20240715", "20240815", "20240916", "20241015"}},
+		{"date=20240515&repeat=" + url.QueryEscape("RRULE:FREQ=MONTHLY;COUNT=3") + "&shift=prev",
+			[]any{"20240515", "20240614", "20240715"}},
+	}
+	for _, v := range tbl {
+		m := getOccurrences(t, v.query)
+		assert.Empty(t, m["error"], v.query)
+		assert.Equal(t, v.want, m["dates"], v.query)
+	}
+}